@@ -14,7 +14,10 @@ BINARY_NAME=bootstrap
 ZIP_FILE=deployment.zip
 
 echo "Building Go binary for Linux ARM64..."
-go build -o $BINARY_NAME main.go
+# Build information reported by /version
+COMMIT=$(git rev-parse HEAD)
+BUILD_TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+go build -ldflags "-X main.commit=$COMMIT -X main.buildTime=$BUILD_TIME" -o $BINARY_NAME .
 
 echo "Packaging the binary into $ZIP_FILE..."
 # -j flag ensures that the zip does not include directory structure
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/metrics"
+	"github.com/skyrenx/blog-api-go/http/tracing"
+)
+
+// lambdaEvent has the fields that tell the supported event shapes apart.
+type lambdaEvent struct {
+	Version        string `json:"version"`
+	RequestContext struct {
+		ELB json.RawMessage `json:"elb"`
+	} `json:"requestContext"`
+}
+
+// lambdaHandler passes API Gateway REST API (payload 1.0), HTTP API (payload 2.0),
+// Lambda Function URL and ALB target group events to the router.
+// The shape of every event is detected, so one function can sit behind any of them.
+type lambdaHandler struct {
+	v1  *ginadapter.GinLambda
+	v2  *ginadapter.GinLambdaV2
+	alb *ginadapter.GinLambdaALB
+}
+
+func newLambdaHandler(router *gin.Engine) *lambdaHandler {
+	return &lambdaHandler{
+		v1:  ginadapter.New(router),
+		v2:  ginadapter.NewV2(router),
+		alb: ginadapter.NewALB(router),
+	}
+}
+
+// handle passes an event to the router. The metrics recorded and the spans ended meanwhile
+// are exported when it returns, before the execution environment is frozen.
+func (h *lambdaHandler) handle(ctx context.Context, payload json.RawMessage) (any, error) {
+	defer flushTelemetry(ctx)
+	var event lambdaEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+	switch {
+	case event.RequestContext.ELB != nil:
+		var request events.ALBTargetGroupRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, fmt.Errorf("failed to parse ALB event: %w", err)
+		}
+		return h.proxyALB(ctx, request)
+	case event.Version == "2.0":
+		// HTTP APIs and Function URLs share the payload format 2.0.
+		var request events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, fmt.Errorf("failed to parse payload 2.0 event: %w", err)
+		}
+		return h.v2.ProxyWithContext(ctx, request)
+	default:
+		var request events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, fmt.Errorf("failed to parse API Gateway event: %w", err)
+		}
+		return h.v1.ProxyWithContext(ctx, request)
+	}
+}
+
+// proxyALB makes ALB events behave like the API Gateway events where the adapter differs.
+func (h *lambdaHandler) proxyALB(ctx context.Context, request events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	// ALB passes the query string as it was sent, but the adapter escapes it again.
+	request.QueryStringParameters = unescapeQuery(request.QueryStringParameters)
+	request.MultiValueQueryStringParameters = unescapeMultiValueQuery(request.MultiValueQueryStringParameters)
+	// The adapter takes the host from the single value headers only.
+	multiValueHeaders := request.MultiValueHeaders != nil
+	if multiValueHeaders && len(request.MultiValueHeaders["host"]) > 0 {
+		headers := make(map[string]string, len(request.Headers)+1)
+		maps.Copy(headers, request.Headers)
+		headers["host"] = request.MultiValueHeaders["host"][0]
+		request.Headers = headers
+	}
+
+	response, err := h.alb.ProxyWithContext(ctx, request)
+	if err != nil {
+		return response, err
+	}
+	response.StatusDescription = fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode))
+	// A target group without multi value headers ignores them in responses.
+	if !multiValueHeaders {
+		response.Headers = make(map[string]string, len(response.MultiValueHeaders))
+		for key, values := range response.MultiValueHeaders {
+			if len(values) == 0 {
+				continue
+			}
+			if strings.EqualFold(key, "Set-Cookie") {
+				// Cookies cannot be joined. Header names are case insensitive, so every cookie
+				// is sent under its own spelling of the name.
+				for i, value := range values {
+					response.Headers[headerCase(key, i)] = value
+				}
+			} else {
+				response.Headers[key] = strings.Join(values, ", ")
+			}
+		}
+		response.MultiValueHeaders = nil
+	}
+	return response, nil
+}
+
+// headerCase spells name in lower case, except for the letters whose bit is set in n.
+func headerCase(name string, n int) string {
+	spelling := []byte(strings.ToLower(name))
+	for i := range spelling {
+		if spelling[i] < 'a' || spelling[i] > 'z' {
+			continue
+		}
+		if n&1 == 1 {
+			spelling[i] -= 'a' - 'A'
+		}
+		n >>= 1
+	}
+	return string(spelling)
+}
+
+func unescapeQuery(query map[string]string) map[string]string {
+	if query == nil {
+		return nil
+	}
+	unescaped := make(map[string]string, len(query))
+	for key, value := range query {
+		unescaped[unescape(key)] = unescape(value)
+	}
+	return unescaped
+}
+
+func unescapeMultiValueQuery(query map[string][]string) map[string][]string {
+	if query == nil {
+		return nil
+	}
+	unescaped := make(map[string][]string, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			unescaped[unescape(key)] = append(unescaped[unescape(key)], unescape(value))
+		}
+	}
+	return unescaped
+}
+
+// unescape decodes a query string part, parts that are not valid escapes are kept as they are.
+func unescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+func flushTelemetry(ctx context.Context) {
+	if err := metrics.FlushEMF(os.Stdout); err != nil {
+		slog.WarnContext(ctx, "Failed to write metrics", "error", err)
+	}
+	if err := tracing.Flush(ctx); err != nil {
+		slog.WarnContext(ctx, "Failed to export spans", "error", err)
+	}
+}
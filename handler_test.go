@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/ratelimit"
+)
+
+// echo is what the test router answers with, the request as the router saw it.
+type echo struct {
+	Method  string     `json:"method"`
+	Host    string     `json:"host"`
+	Query   url.Values `json:"query"`
+	Header  []string   `json:"header"`
+	Cookies []string   `json:"cookies"`
+	Body    string     `json:"body"`
+}
+
+// testResponse has the fields of all the response shapes of the supported events.
+type testResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	Cookies           []string            `json:"cookies"`
+	Body              string              `json:"body"`
+}
+
+// setCookies returns the cookies of the response, wherever its event shape puts them.
+func (r testResponse) setCookies() []string {
+	cookies := slices.Clone(r.Cookies)
+	for key, values := range r.MultiValueHeaders {
+		if strings.EqualFold(key, "Set-Cookie") {
+			cookies = append(cookies, values...)
+		}
+	}
+	for key, value := range r.Headers {
+		if strings.EqualFold(key, "Set-Cookie") {
+			cookies = append(cookies, value)
+		}
+	}
+	slices.Sort(cookies)
+	return cookies
+}
+
+func newTestLambdaHandler() *lambdaHandler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Any("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		var cookies []string
+		for _, cookie := range c.Request.Cookies() {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+		http.SetCookie(c.Writer, &http.Cookie{Name: "oidc_flow", Value: "", Path: "/", MaxAge: -1})
+		http.SetCookie(c.Writer, &http.Cookie{Name: "session", Value: "token", Path: "/"})
+		c.JSON(http.StatusOK, echo{
+			Method:  c.Request.Method,
+			Host:    c.Request.Host,
+			Query:   c.Request.URL.Query(),
+			Header:  c.Request.Header.Values("X-Test"),
+			Cookies: cookies,
+			Body:    string(body),
+		})
+	})
+	return newLambdaHandler(router)
+}
+
+func TestLambdaHandler(t *testing.T) {
+	// The query of every event is q=a b&c&tag=x&tag=y and its body "hello", base64 encoded.
+	want := echo{
+		Method:  http.MethodPost,
+		Query:   url.Values{"q": {"a b&c"}, "tag": {"x", "y"}},
+		Header:  []string{"a", "b"},
+		Cookies: []string{"oidc_flow=state", "theme=dark"},
+		Body:    "hello",
+	}
+	tests := []struct {
+		name  string
+		event string
+		// Fields of the echo that differ from want.
+		wantHost   string
+		wantQuery  url.Values
+		wantHeader []string
+	}{
+		{
+			name: "API Gateway REST API",
+			event: `{
+				"resource": "/{proxy+}", "path": "/echo", "httpMethod": "POST",
+				"multiValueHeaders": {"Host": ["api.example.com"], "X-Test": ["a", "b"], "Cookie": ["oidc_flow=state; theme=dark"]},
+				"multiValueQueryStringParameters": {"q": ["a b&c"], "tag": ["x", "y"]},
+				"requestContext": {"domainName": "api.example.com", "stage": "prod", "identity": {"sourceIp": "203.0.113.1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost: "api.example.com",
+		},
+		{
+			name: "API Gateway REST API with single value headers",
+			event: `{
+				"resource": "/{proxy+}", "path": "/echo", "httpMethod": "POST",
+				"headers": {"Host": "api.example.com", "X-Test": "b", "Cookie": "oidc_flow=state; theme=dark"},
+				"queryStringParameters": {"q": "a b&c", "tag": "y"},
+				"requestContext": {"domainName": "api.example.com", "stage": "prod", "identity": {"sourceIp": "203.0.113.1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost:   "api.example.com",
+			wantQuery:  url.Values{"q": {"a b&c"}, "tag": {"y"}},
+			wantHeader: []string{"b"},
+		},
+		{
+			name: "HTTP API",
+			event: `{
+				"version": "2.0", "routeKey": "$default", "rawPath": "/echo", "rawQueryString": "q=a+b%26c&tag=x&tag=y",
+				"cookies": ["oidc_flow=state", "theme=dark"],
+				"headers": {"host": "api.example.com", "x-test": "a,b"},
+				"requestContext": {"domainName": "api.example.com", "http": {"method": "POST", "path": "/echo", "sourceIp": "203.0.113.1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost: "api.example.com",
+		},
+		{
+			name: "Function URL",
+			event: `{
+				"version": "2.0", "routeKey": "$default", "rawPath": "/echo", "rawQueryString": "q=a%20b%26c&tag=x&tag=y",
+				"cookies": ["oidc_flow=state", "theme=dark"],
+				"headers": {"host": "abc.lambda-url.eu-west-1.on.aws", "x-test": "a, b"},
+				"requestContext": {"domainName": "abc.lambda-url.eu-west-1.on.aws", "http": {"method": "POST", "path": "/echo", "sourceIp": "203.0.113.1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost: "abc.lambda-url.eu-west-1.on.aws",
+		},
+		{
+			name: "ALB with multi value headers",
+			event: `{
+				"httpMethod": "POST", "path": "/echo",
+				"multiValueHeaders": {"host": ["lb.example.com"], "x-test": ["a", "b"], "cookie": ["oidc_flow=state; theme=dark"]},
+				"multiValueQueryStringParameters": {"q": ["a%20b%26c"], "tag": ["x", "y"]},
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/blog/1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost: "lb.example.com",
+		},
+		{
+			name: "ALB with single value headers",
+			event: `{
+				"httpMethod": "POST", "path": "/echo",
+				"headers": {"host": "lb.example.com", "x-test": "b", "cookie": "oidc_flow=state; theme=dark"},
+				"queryStringParameters": {"q": "a+b%26c", "tag": "y"},
+				"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/blog/1"}},
+				"body": "aGVsbG8=", "isBase64Encoded": true
+			}`,
+			wantHost:   "lb.example.com",
+			wantQuery:  url.Values{"q": {"a b&c"}, "tag": {"y"}},
+			wantHeader: []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newTestLambdaHandler().handle(context.Background(), json.RawMessage(tt.event))
+			if err != nil {
+				t.Fatalf("handle failed: %v", err)
+			}
+			// Every response shape is read through its JSON, as the Lambda runtime sends it.
+			payload, err := json.Marshal(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var response testResponse
+			if err := json.Unmarshal(payload, &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != http.StatusOK {
+				t.Fatalf("status = %v, want %v: %s", response.StatusCode, http.StatusOK, response.Body)
+			}
+
+			var got echo
+			if err := json.Unmarshal([]byte(response.Body), &got); err != nil {
+				t.Fatalf("body is not an echo: %v: %s", err, response.Body)
+			}
+			want := want
+			want.Host = tt.wantHost
+			if tt.wantQuery != nil {
+				want.Query = tt.wantQuery
+			}
+			if tt.wantHeader != nil {
+				want.Header = tt.wantHeader
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("request = %+v, want %+v", got, want)
+			}
+
+			wantCookies := []string{"oidc_flow=; Path=/; Max-Age=0", "session=token; Path=/"}
+			if cookies := response.setCookies(); !slices.Equal(cookies, wantCookies) {
+				t.Errorf("cookies = %q, want %q", cookies, wantCookies)
+			}
+		})
+	}
+}
+
+func TestLambdaHandlerClientIP(t *testing.T) {
+	tests := []struct {
+		name string
+		// event returns a request of the client ip.
+		event func(ip string) string
+	}{
+		{
+			name: "API Gateway REST API",
+			event: func(ip string) string {
+				return `{"path": "/ip", "httpMethod": "GET", "headers": {"X-Forwarded-For": "192.0.2.1"},
+					"requestContext": {"domainName": "api.example.com", "identity": {"sourceIp": "` + ip + `"}}}`
+			},
+		},
+		{
+			name: "HTTP API",
+			event: func(ip string) string {
+				return `{"version": "2.0", "rawPath": "/ip", "headers": {"x-forwarded-for": "192.0.2.1"},
+					"requestContext": {"domainName": "api.example.com", "http": {"method": "GET", "path": "/ip", "sourceIp": "` + ip + `"}}}`
+			},
+		},
+		{
+			name: "Function URL",
+			event: func(ip string) string {
+				return `{"version": "2.0", "rawPath": "/ip", "headers": {"x-forwarded-for": "192.0.2.1"},
+					"requestContext": {"domainName": "abc.lambda-url.eu-west-1.on.aws", "http": {"method": "GET", "path": "/ip", "sourceIp": "` + ip + `"}}}`
+			},
+		},
+		{
+			name: "ALB",
+			event: func(ip string) string {
+				// The first entry was sent by the client, the ALB appended the last one.
+				return `{"httpMethod": "GET", "path": "/ip", "headers": {"host": "lb.example.com", "x-forwarded-for": "192.0.2.1, ` + ip + `"},
+					"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/blog/1"}}}`
+			},
+		},
+		{
+			name: "ALB with multi value headers",
+			event: func(ip string) string {
+				return `{"httpMethod": "GET", "path": "/ip", "multiValueHeaders": {"host": ["lb.example.com"], "x-forwarded-for": ["192.0.2.1, ` + ip + `"]},
+					"requestContext": {"elb": {"targetGroupArn": "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/blog/1"}}}`
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(middleware.ErrorResponse(), middleware.RateLimit(ratelimit.NewMemoryStore(), middleware.RateLimitPolicy{
+				Name: "client", Limit: ratelimit.Limit{Rate: 0.001, Burst: 1}, Key: middleware.ClientIPKey}))
+			router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, middleware.ClientIP(c)) })
+			handler := newLambdaHandler(router)
+
+			// Every client has a bucket of its own, which allows a single request.
+			requests := []struct {
+				ip         string
+				wantStatus int
+			}{
+				{ip: "203.0.113.1", wantStatus: http.StatusOK},
+				{ip: "203.0.113.2", wantStatus: http.StatusOK},
+				{ip: "203.0.113.1", wantStatus: http.StatusTooManyRequests},
+			}
+			for _, request := range requests {
+				result, err := handler.handle(context.Background(), json.RawMessage(tt.event(request.ip)))
+				if err != nil {
+					t.Fatalf("handle failed: %v", err)
+				}
+				payload, err := json.Marshal(result)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var response testResponse
+				if err := json.Unmarshal(payload, &response); err != nil {
+					t.Fatal(err)
+				}
+				if response.StatusCode != request.wantStatus {
+					t.Errorf("status of %v = %v, want %v", request.ip, response.StatusCode, request.wantStatus)
+				}
+				if response.StatusCode == http.StatusOK && response.Body != request.ip {
+					t.Errorf("client IP = %q, want %v", response.Body, request.ip)
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderCase(t *testing.T) {
+	seen := make(map[string]bool)
+	for n := range 8 {
+		spelling := headerCase("Set-Cookie", n)
+		if !strings.EqualFold(spelling, "Set-Cookie") {
+			t.Errorf("headerCase(%v) = %q, want a spelling of Set-Cookie", n, spelling)
+		}
+		if seen[spelling] {
+			t.Errorf("headerCase(%v) = %q, want a new spelling", n, spelling)
+		}
+		seen[spelling] = true
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "hello", want: "hello"},
+		{s: "a%20b", want: "a b"},
+		{s: "a+b", want: "a b"},
+		{s: "a%26b%3Dc", want: "a&b=c"},
+		{s: "%C3%A9t%C3%A9", want: "été"},
+		{s: "100%", want: "100%"},
+		{s: "%zz", want: "%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := unescape(tt.s); got != tt.want {
+				t.Errorf("unescape(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeMultiValueQuery(t *testing.T) {
+	query := map[string][]string{"q": {"a%20b"}, "tag%5B%5D": {"x", "y%2Bz"}}
+	want := map[string][]string{"q": {"a b"}, "tag[]": {"x", "y+z"}}
+	if got := unescapeMultiValueQuery(query); !reflect.DeepEqual(got, want) {
+		t.Errorf("unescapeMultiValueQuery() = %v, want %v", got, want)
+	}
+	if got := unescapeMultiValueQuery(nil); got != nil {
+		t.Errorf("unescapeMultiValueQuery(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,122 @@
+// Package apperror defines the errors the client caused or can act on.
+// Repositories and services return them, the ErrorResponse middleware maps their kind to an HTTP status.
+// Every other error is reported as an internal error without details.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Kind int
+
+const (
+	NOT_FOUND Kind = iota + 1
+	CONFLICT
+	VALIDATION
+	UNAUTHORIZED
+	FORBIDDEN
+	TOO_MANY_REQUESTS
+	TOO_LARGE
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NOT_FOUND:
+		return "not found"
+	case CONFLICT:
+		return "conflict"
+	case VALIDATION:
+		return "validation"
+	case UNAUTHORIZED:
+		return "unauthorized"
+	case FORBIDDEN:
+		return "forbidden"
+	case TOO_MANY_REQUESTS:
+		return "too many requests"
+	case TOO_LARGE:
+		return "too large"
+	default:
+		return fmt.Sprintf("kind %d", int(k))
+	}
+}
+
+// Error is returned to the client with its Message, which must not reveal internals.
+// Err is the underlying cause and is only logged.
+type Error struct {
+	Kind    Kind
+	Message string
+	// The invalid fields of a VALIDATION error, if known.
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError names a field of the request and what is wrong with it.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of the error caused by err, so shared errors stay unchanged.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func NotFound(format string, args ...any) *Error {
+	return newError(NOT_FOUND, format, args...)
+}
+
+func Conflict(format string, args ...any) *Error {
+	return newError(CONFLICT, format, args...)
+}
+
+func Validation(format string, args ...any) *Error {
+	return newError(VALIDATION, format, args...)
+}
+
+func Unauthorized(format string, args ...any) *Error {
+	return newError(UNAUTHORIZED, format, args...)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return newError(FORBIDDEN, format, args...)
+}
+
+func TooManyRequests(format string, args ...any) *Error {
+	return newError(TOO_MANY_REQUESTS, format, args...)
+}
+
+func TooLarge(format string, args ...any) *Error {
+	return newError(TOO_LARGE, format, args...)
+}
+
+// InvalidFields is a VALIDATION error listing the invalid fields of the request.
+func InvalidFields(fields ...FieldError) *Error {
+	return &Error{Kind: VALIDATION, Message: "request has invalid fields", Fields: fields}
+}
+
+// KindOf returns the kind of the first Error in the chain of err, or 0 if there is none.
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return 0
+}
+
+func newError(kind Kind, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,538 @@
+// Package config loads the settings of the api once at startup.
+//
+// Every setting has a default and can be set, in increasing order of precedence,
+// in the file named by CONFIG_FILE or -config, which has KEY=value lines like a .env file,
+// as an environment variable, or as a flag named like the variable in lower case
+// with dashes, e.g. -db-backend for DB_BACKEND. Empty environment variables count as unset.
+//
+// JWT_SECRET, DATABASE_URL and OIDC_CLIENT_SECRET may be references to secrets, see package secrets.
+//
+// The settings are plain data, config depends on none of the packages it configures.
+// Each package builds its options from its section, nothing else reads the environment.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/joho/godotenv"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const CONFIG_FILE = "CONFIG_FILE"
+
+// Database backends selectable with DB_BACKEND.
+const (
+	BACKEND_DSQL     = "dsql"
+	BACKEND_POSTGRES = "postgres"
+	BACKEND_SQLITE   = "sqlite"
+	BACKEND_MEMORY   = "memory"
+)
+
+// Password hash algorithms selectable with PASSWORD_HASH_ALGORITHM.
+const (
+	ALGORITHM_BCRYPT   = "bcrypt"
+	ALGORITHM_ARGON2ID = "argon2id"
+)
+
+// Span exporters selectable with TRACING_EXPORTER.
+const (
+	EXPORTER_NONE   = "none"
+	EXPORTER_OTLP   = "otlp"
+	EXPORTER_STDOUT = "stdout"
+)
+
+// Allows any origin in CORSConfig.AllowedOrigins.
+const ANY_ORIGIN = "*"
+
+type Config struct {
+	Region                 string // AWS region, AWS_REGION
+	LogLevel               slog.Level
+	BlogEntryIDStrategy    string        // One of the entities.ID_STRATEGY_* constants
+	SecretsRefreshInterval time.Duration // Age after which referenced secrets are fetched again
+	MetricsNamespace       string        // CloudWatch namespace of the metrics emitted in Lambda
+	Database               DatabaseConfig
+	HTTP                   HTTPConfig
+	Tokens                 TokenConfig
+	Passwords              PasswordConfig
+	OIDC                   OIDCConfig
+	Tracing                TracingConfig
+	CORS                   CORSConfig
+	RateLimit              RateLimitConfig
+	Features               FeatureConfig
+}
+
+// DatabaseConfig selects the database backend and where to find it.
+type DatabaseConfig struct {
+	Backend         string        // One of the BACKEND_* constants
+	ClusterEndpoint string        // Aurora DSQL cluster endpoint for BACKEND_DSQL
+	Region          string        // AWS region of the Aurora DSQL cluster
+	DatabaseURL     string        // Postgres DSN for BACKEND_POSTGRES, database file path for BACKEND_SQLITE
+	QueryTimeout    time.Duration // Limit of a single database operation
+}
+
+// HTTPConfig configures the standalone server and the handling of every request.
+type HTTPConfig struct {
+	Addr            string
+	TLSCertFile     string
+	TLSKeyFile      string
+	ShutdownTimeout time.Duration // Time open requests get to finish after SIGTERM
+	RequestTimeout  time.Duration // Applies when the Lambda invocation has no deadline
+	MaxBodySize     int           // Largest request body in bytes
+}
+
+// TokenConfig configures the tokens issued by the api.
+type TokenConfig struct {
+	Secret   string        // HMAC key of the tokens or a reference to it
+	Lifetime time.Duration // Time a token is valid
+}
+
+// PasswordConfig configures how passwords are hashed and which passwords are accepted.
+type PasswordConfig struct {
+	HashAlgorithm        string // ALGORITHM_BCRYPT or ALGORITHM_ARGON2ID
+	BcryptCost           int
+	MinLength            int    // In characters
+	MaxLength            int    // In bytes
+	BreachedPasswordList string // Path of a list of breached passwords, none if empty
+}
+
+// OIDCConfig configures the sign in with an external identity provider, which is disabled without an issuer.
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string // Client secret or a reference to it
+	RedirectURL   string // URL of /User/oidc/callback
+	Scopes        []string
+	UsernameClaim string // Claim of the ID token that names new users
+}
+
+// TracingConfig selects where spans are exported, tracing is disabled with EXPORTER_NONE.
+type TracingConfig struct {
+	Exporter     string  // One of the EXPORTER_* constants
+	OTLPEndpoint string  // URL of the OTLP/HTTP receiver, e.g. of a local collector
+	ServiceName  string  // service.name of the spans
+	SampleRatio  float64 // Share of the traces started by the api that are recorded, 0 to 1
+}
+
+// CORSConfig lists the browser origins that may call the api, CORS is disabled without any.
+type CORSConfig struct {
+	AllowedOrigins   []string      // Origins like https://example.com, or ANY_ORIGIN
+	AllowedMethods   []string      // Methods the allowed origins may use
+	AllowCredentials bool          // Let browsers send cookies and the Authorization header of the user
+	MaxAge           time.Duration // Time browsers may cache a preflight response
+}
+
+// RateLimitConfig limits the requests of clients, a limit with a rate of 0 is disabled.
+type RateLimitConfig struct {
+	Client Limit // Requests of a client IP to any route
+	User   Limit // Requests of an authenticated user or api key to the routes that require authentication
+	// Requests of a client IP to single routes, keyed by method and route, e.g. "POST /User/register".
+	Routes map[string]Limit
+}
+
+// Limit is a token bucket, see package ratelimit.
+type Limit struct {
+	Rate  float64 // Requests per second
+	Burst int     // Requests that may be sent at once
+}
+
+// FeatureConfig toggles optional behavior.
+type FeatureConfig struct {
+	MigrateOnStartup bool // Apply pending migrations before serving requests
+	Registration     bool // Allow anyone to register a user
+}
+
+// setting is a single configuration value, named like its environment variable.
+type setting struct {
+	name         string
+	defaultValue string
+	usage        string
+	set          func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"AWS_REGION", "us-east-1", "AWS region of the Aurora DSQL cluster and of AWS services",
+		func(c *Config, value string) error { c.Region, c.Database.Region = value, value; return nil }},
+	{"LOG_LEVEL", "info", "log level: debug, info, warn or error",
+		func(c *Config, value string) error { return c.LogLevel.UnmarshalText([]byte(value)) }},
+	{"BLOG_ENTRY_ID_STRATEGY", entities.ID_STRATEGY_SEQUENCE, "ID strategy of new blog entries: sequence or uuidv7",
+		stringValue(func(c *Config) *string { return &c.BlogEntryIDStrategy })},
+
+	{"SECRETS_REFRESH_INTERVAL", "5m", "age after which referenced secrets are fetched again",
+		durationValue(func(c *Config) *time.Duration { return &c.SecretsRefreshInterval })},
+	{"METRICS_NAMESPACE", "BlogApi", "CloudWatch namespace of the metrics emitted in Lambda",
+		stringValue(func(c *Config) *string { return &c.MetricsNamespace })},
+
+	{"DB_BACKEND", BACKEND_DSQL, "database backend: dsql, postgres, sqlite or memory",
+		stringValue(func(c *Config) *string { return &c.Database.Backend })},
+	{"CLUSTER_ENDPOINT", "", "Aurora DSQL cluster endpoint",
+		stringValue(func(c *Config) *string { return &c.Database.ClusterEndpoint })},
+	{"DATABASE_URL", "", "Postgres DSN or SQLite database file",
+		stringValue(func(c *Config) *string { return &c.Database.DatabaseURL })},
+	{"DB_QUERY_TIMEOUT", "5s", "limit of a single database operation",
+		durationValue(func(c *Config) *time.Duration { return &c.Database.QueryTimeout })},
+
+	{"HTTP_ADDR", ":8080", "address of the standalone server",
+		stringValue(func(c *Config) *string { return &c.HTTP.Addr })},
+	{"HTTP_TLS_CERT_FILE", "", "TLS certificate of the standalone server",
+		stringValue(func(c *Config) *string { return &c.HTTP.TLSCertFile })},
+	{"HTTP_TLS_KEY_FILE", "", "TLS key of the standalone server",
+		stringValue(func(c *Config) *string { return &c.HTTP.TLSKeyFile })},
+	{"HTTP_SHUTDOWN_TIMEOUT", "30s", "time open requests get to finish after SIGTERM",
+		durationValue(func(c *Config) *time.Duration { return &c.HTTP.ShutdownTimeout })},
+	{"REQUEST_TIMEOUT", "30s", "request timeout when the Lambda invocation has no deadline",
+		durationValue(func(c *Config) *time.Duration { return &c.HTTP.RequestTimeout })},
+	{"HTTP_MAX_BODY_SIZE", strconv.Itoa(1 << 20), "largest request body in bytes",
+		intValue(func(c *Config) *int { return &c.HTTP.MaxBodySize })},
+
+	{"JWT_SECRET", "", "HMAC key of the issued tokens, required",
+		stringValue(func(c *Config) *string { return &c.Tokens.Secret })},
+	{"JWT_LIFETIME", "24h", "time an issued token is valid",
+		durationValue(func(c *Config) *time.Duration { return &c.Tokens.Lifetime })},
+
+	{"PASSWORD_HASH_ALGORITHM", ALGORITHM_BCRYPT, "password hash algorithm: bcrypt or argon2id",
+		stringValue(func(c *Config) *string { return &c.Passwords.HashAlgorithm })},
+	{"PASSWORD_BCRYPT_COST", strconv.Itoa(bcrypt.DefaultCost), "bcrypt cost of new password hashes",
+		intValue(func(c *Config) *int { return &c.Passwords.BcryptCost })},
+	{"PASSWORD_MIN_LENGTH", "8", "minimum password length in characters",
+		intValue(func(c *Config) *int { return &c.Passwords.MinLength })},
+	{"PASSWORD_MAX_LENGTH", "72", "maximum password length in bytes",
+		intValue(func(c *Config) *int { return &c.Passwords.MaxLength })},
+	{"PASSWORD_BREACHED_LIST", "", "file of breached passwords that are rejected",
+		stringValue(func(c *Config) *string { return &c.Passwords.BreachedPasswordList })},
+
+	{"OIDC_ISSUER", "", "issuer of the OpenID Connect provider, sign in with it is disabled if empty",
+		stringValue(func(c *Config) *string { return &c.OIDC.Issuer })},
+	{"OIDC_CLIENT_ID", "", "client ID at the OpenID Connect provider",
+		stringValue(func(c *Config) *string { return &c.OIDC.ClientID })},
+	{"OIDC_CLIENT_SECRET", "", "client secret at the OpenID Connect provider",
+		stringValue(func(c *Config) *string { return &c.OIDC.ClientSecret })},
+	{"OIDC_REDIRECT_URL", "", "URL of /User/oidc/callback",
+		stringValue(func(c *Config) *string { return &c.OIDC.RedirectURL })},
+	{"OIDC_SCOPES", "openid profile email", "requested scopes",
+		listValue(func(c *Config) *[]string { return &c.OIDC.Scopes })},
+	{"OIDC_USERNAME_CLAIM", "preferred_username", "claim of the ID token that names new users",
+		stringValue(func(c *Config) *string { return &c.OIDC.UsernameClaim })},
+
+	{"CORS_ALLOWED_ORIGINS", "", "origins allowed to call the api from a browser, e.g. https://example.com",
+		listValue(func(c *Config) *[]string { return &c.CORS.AllowedOrigins })},
+	{"CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE", "methods the allowed origins may use",
+		listValue(func(c *Config) *[]string { return &c.CORS.AllowedMethods })},
+	{"CORS_ALLOW_CREDENTIALS", "false", "allow browsers to send credentials with cross origin requests",
+		boolValue(func(c *Config) *bool { return &c.CORS.AllowCredentials })},
+	{"CORS_MAX_AGE", "10m", "time browsers may cache a preflight response",
+		durationValue(func(c *Config) *time.Duration { return &c.CORS.MaxAge })},
+
+	{"TRACING_EXPORTER", EXPORTER_NONE, "where spans are exported: none, otlp or stdout",
+		stringValue(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"TRACING_OTLP_ENDPOINT", "http://localhost:4318", "URL of the OTLP/HTTP receiver of the spans",
+		stringValue(func(c *Config) *string { return &c.Tracing.OTLPEndpoint })},
+	{"TRACING_SERVICE_NAME", "blog-api-go", "service name of the spans",
+		stringValue(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"TRACING_SAMPLE_RATIO", "1", "share of new traces that are recorded, from 0 to 1",
+		floatValue(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+
+	{"RATE_LIMIT_REQUESTS_PER_SECOND", "0", "requests per second of a client IP, 0 disables the limit",
+		floatValue(func(c *Config) *float64 { return &c.RateLimit.Client.Rate })},
+	{"RATE_LIMIT_BURST", "20", "requests a client IP may send at once",
+		intValue(func(c *Config) *int { return &c.RateLimit.Client.Burst })},
+	{"RATE_LIMIT_USER_REQUESTS_PER_SECOND", "0", "requests per second of an authenticated user or api key, 0 disables the limit",
+		floatValue(func(c *Config) *float64 { return &c.RateLimit.User.Rate })},
+	{"RATE_LIMIT_USER_BURST", "20", "requests an authenticated user or api key may send at once",
+		intValue(func(c *Config) *int { return &c.RateLimit.User.Burst })},
+	{"RATE_LIMIT_ROUTES", "POST:/User/register=0.05/5 GET:/User/login=0.5/10 POST:/User/password=0.2/5",
+		"limits of a client IP on single routes, as METHOD:/route=rate/burst",
+		routeLimitsValue(func(c *Config) *map[string]Limit { return &c.RateLimit.Routes })},
+
+	{"DB_MIGRATE_ON_STARTUP", "false", "apply pending migrations before serving requests",
+		boolValue(func(c *Config) *bool { return &c.Features.MigrateOnStartup })},
+	{"REGISTRATION_ENABLED", "true", "allow anyone to register a user",
+		boolValue(func(c *Config) *bool { return &c.Features.Registration })},
+}
+
+// Load reads the settings from their defaults, the config file, the environment and args,
+// and returns the arguments left after the flags, e.g. a subcommand.
+// Values that cannot be parsed are errors, the settings are checked by Validate.
+func Load(args []string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("blog-api-go", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(CONFIG_FILE), "file with KEY=value lines, "+CONFIG_FILE)
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.name] = flags.String(flagName(s.name), s.defaultValue, s.usage+", "+s.name)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string, len(settings))
+	for _, s := range settings {
+		values[s.name] = s.defaultValue
+	}
+	if *configFile != "" {
+		fileValues, err := godotenv.Read(*configFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		var unknown []string
+		for name, value := range fileValues {
+			if _, ok := values[name]; !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			values[name] = value
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, nil, fmt.Errorf("unknown settings in config file: %v", strings.Join(unknown, ", "))
+		}
+	}
+	for _, s := range settings {
+		if value := os.Getenv(s.name); value != "" {
+			values[s.name] = value
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.name) == f.Name {
+				values[s.name] = *flagValues[s.name]
+			}
+		}
+	})
+
+	config := &Config{}
+	var errs []error
+	for _, s := range settings {
+		if err := s.set(config, values[s.name]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %v: %w", s.name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	return config, flags.Args(), nil
+}
+
+// Validate checks everything needed to serve requests, so a misconfigured api fails at startup.
+func (c *Config) Validate() error {
+	errs := []error{c.Database.Validate(), c.Tokens.Validate(), c.Passwords.Validate(), c.OIDC.Validate(),
+		c.Tracing.Validate(), c.CORS.Validate(), c.RateLimit.Validate()}
+	switch c.BlogEntryIDStrategy {
+	case entities.ID_STRATEGY_SEQUENCE, entities.ID_STRATEGY_UUIDV7:
+	default:
+		errs = append(errs, fmt.Errorf("unknown blog entry id strategy: %v", c.BlogEntryIDStrategy))
+	}
+	if c.HTTP.MaxBodySize < 1 {
+		errs = append(errs, errors.New("HTTP_MAX_BODY_SIZE must be positive"))
+	}
+	if (c.HTTP.TLSCertFile == "") != (c.HTTP.TLSKeyFile == "") {
+		errs = append(errs, errors.New("HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE must be set together"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the backend is known and has the settings it needs.
+func (c DatabaseConfig) Validate() error {
+	switch c.Backend {
+	case BACKEND_DSQL:
+		if c.ClusterEndpoint == "" {
+			return errors.New("CLUSTER_ENDPOINT is not set")
+		}
+	case BACKEND_POSTGRES, BACKEND_SQLITE:
+		if c.DatabaseURL == "" {
+			return errors.New("DATABASE_URL is not set")
+		}
+	case BACKEND_MEMORY:
+	default:
+		return fmt.Errorf("unknown database backend: %v", c.Backend)
+	}
+	return nil
+}
+
+// Validate checks that a secret is set.
+func (c TokenConfig) Validate() error {
+	if c.Secret == "" {
+		return errors.New("JWT_SECRET is not set")
+	}
+	return nil
+}
+
+// Validate checks the algorithm and its parameters.
+func (c PasswordConfig) Validate() error {
+	var errs []error
+	switch c.HashAlgorithm {
+	case ALGORITHM_BCRYPT, ALGORITHM_ARGON2ID:
+	default:
+		errs = append(errs, fmt.Errorf("unknown password hash algorithm: %v", c.HashAlgorithm))
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		errs = append(errs, fmt.Errorf("bcrypt cost must be between %v and %v", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.MinLength < 1 || c.MaxLength < 1 {
+		errs = append(errs, errors.New("password lengths must be positive"))
+	} else if c.MinLength > c.MaxLength {
+		errs = append(errs, errors.New("minimum password length is greater than the maximum"))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that an enabled provider has a client.
+func (c OIDCConfig) Validate() error {
+	if c.Issuer == "" {
+		return nil
+	}
+	if c.ClientID == "" {
+		return errors.New("OIDC_CLIENT_ID is not set")
+	}
+	if c.RedirectURL == "" {
+		return errors.New("OIDC_REDIRECT_URL is not set")
+	}
+	return nil
+}
+
+// Validate checks the exporter and the sample ratio.
+func (c TracingConfig) Validate() error {
+	switch c.Exporter {
+	case EXPORTER_NONE, EXPORTER_OTLP, EXPORTER_STDOUT:
+	default:
+		return fmt.Errorf("unknown tracing exporter: %v", c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return errors.New("tracing sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Validate checks that the origins are scheme and host only, and that credentials are not allowed for any origin.
+func (c CORSConfig) Validate() error {
+	var errs []error
+	for _, origin := range c.AllowedOrigins {
+		if origin == ANY_ORIGIN {
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("CORS credentials cannot be allowed for any origin"))
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("invalid CORS origin: %v", origin))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the limits of clients and users are disabled or positive.
+// The limits of routes are checked when they are parsed.
+func (c RateLimitConfig) Validate() error {
+	for _, limit := range []Limit{c.Client, c.User} {
+		if limit.Rate < 0 || (limit.Rate > 0 && limit.Burst < 1) {
+			return errors.New("rate limits must be positive")
+		}
+	}
+	return nil
+}
+
+// flagName returns the flag of a setting, e.g. db-backend for DB_BACKEND.
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+func stringValue(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// Lists are separated by commas or whitespace.
+func listValue(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+		return nil
+	}
+}
+
+func boolValue(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.ParseBool(value)
+		return err
+	}
+}
+
+func intValue(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func floatValue(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.ParseFloat(value, 64)
+		return err
+	}
+}
+
+// Route limits are a list of METHOD:/route=rate/burst, the route as it is registered,
+// e.g. "GET:/BlogEntry/:id=5/20".
+func routeLimitsValue(field func(c *Config) *map[string]Limit) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		limits := make(map[string]Limit)
+		for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			method, rest, _ := strings.Cut(entry, ":")
+			separator := strings.LastIndex(rest, "=")
+			if method == "" || !strings.HasPrefix(rest, "/") || separator < 0 {
+				return fmt.Errorf("route limit %q is not METHOD:/route=rate/burst", entry)
+			}
+			limit, err := parseLimit(rest[separator+1:])
+			if err != nil {
+				return err
+			}
+			limits[strings.ToUpper(method)+" "+rest[:separator]] = limit
+		}
+		*field(c) = limits
+		return nil
+	}
+}
+
+// Limits are written as "rate/burst", e.g. "0.5/10" for a burst of 10 and one request every 2 seconds.
+func parseLimit(value string) (Limit, error) {
+	rate, burst, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("limit %q is not rate/burst", value)
+	}
+	var limit Limit
+	var err error
+	if limit.Rate, err = strconv.ParseFloat(rate, 64); err != nil || limit.Rate <= 0 || math.IsInf(limit.Rate, 0) {
+		return Limit{}, fmt.Errorf("limit %q has an invalid rate", value)
+	}
+	if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst < 1 {
+		return Limit{}, fmt.Errorf("limit %q has an invalid burst", value)
+	}
+	return limit, nil
+}
+
+// Durations are written like "5s" or "1h30m" and must be positive.
+func durationValue(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if duration <= 0 {
+			return errors.New("must be positive")
+		}
+		*field(c) = duration
+		return nil
+	}
+}
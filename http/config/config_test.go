@@ -0,0 +1,62 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestRouteLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]Limit
+		wantErr bool
+	}{
+		{name: "none", value: "", want: map[string]Limit{}},
+		{
+			name:  "single route",
+			value: "POST:/User/register=0.05/5",
+			want:  map[string]Limit{"POST /User/register": {Rate: 0.05, Burst: 5}},
+		},
+		{
+			name:  "separated by commas and spaces",
+			value: "get:/User/login=0.5/10, POST:/User/password=0.2/5",
+			want: map[string]Limit{
+				"GET /User/login":     {Rate: 0.5, Burst: 10},
+				"POST /User/password": {Rate: 0.2, Burst: 5},
+			},
+		},
+		{
+			name:  "route with a parameter",
+			value: "GET:/BlogEntry/:id=5/20",
+			want:  map[string]Limit{"GET /BlogEntry/:id": {Rate: 5, Burst: 20}},
+		},
+		{name: "missing method", value: ":/User/login=1/1", wantErr: true},
+		{name: "missing route", value: "GET=1/1", wantErr: true},
+		{name: "relative route", value: "GET:User/login=1/1", wantErr: true},
+		{name: "missing limit", value: "GET:/User/login", wantErr: true},
+		{name: "missing burst", value: "GET:/User/login=1", wantErr: true},
+		{name: "zero rate", value: "GET:/User/login=0/1", wantErr: true},
+		{name: "negative rate", value: "GET:/User/login=-1/1", wantErr: true},
+		{name: "infinite rate", value: "GET:/User/login=Inf/1", wantErr: true},
+		{name: "zero burst", value: "GET:/User/login=1/0", wantErr: true},
+		{name: "fractional burst", value: "GET:/User/login=1/1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _, err := Load([]string{"-rate-limit-routes", tt.value})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load succeeded with %v", config.RateLimit.Routes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if !maps.Equal(config.RateLimit.Routes, tt.want) {
+				t.Errorf("routes = %v, want %v", config.RateLimit.Routes, tt.want)
+			}
+		})
+	}
+}
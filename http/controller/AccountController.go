@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+type AccountController struct {
+	accounts *service.AccountService
+}
+
+func NewAccountController(accounts *service.AccountService) *AccountController {
+	return &AccountController{accounts: accounts}
+}
+
+func (ctl *AccountController) ExportAccount(c *gin.Context) {
+	username := middleware.GetPrincipal(c).Username
+	export, err := ctl.accounts.ExportAccount(c.Request.Context(), username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%v-export.json"`, username))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+func (ctl *AccountController) DeleteAccount(c *gin.Context) {
+	var request dto.AccountDeletionRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = dto.ACCOUNT_DELETION_MODE_DELETE
+	}
+	username := middleware.GetPrincipal(c).Username
+	if err := ctl.accounts.ValidateAccountDeletionRequest(username, request); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := ctl.accounts.DeleteAccount(c.Request.Context(), username, request); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+type AdminController struct {
+	admin *service.AdminService
+}
+
+func NewAdminController(admin *service.AdminService) *AdminController {
+	return &AdminController{admin: admin}
+}
+
+func (ctl *AdminController) SearchUsers(c *gin.Context) {
+	pageNumber, _ := strconv.Atoi(c.DefaultQuery("pageNumber", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	users, totalPages, err := ctl.admin.SearchUsers(c.Request.Context(), c.Query("search"), pageNumber, pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users, "page_count": totalPages})
+}
+
+func (ctl *AdminController) GetUserAccount(c *gin.Context) {
+	account, err := ctl.admin.GetUserAccount(c.Request.Context(), c.Param("username"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if account == nil {
+		c.Error(apperror.NotFound("user not found: %v", c.Param("username")))
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func (ctl *AdminController) SetUserEnabled(c *gin.Context) {
+	var request dto.UserEnabledRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	found, err := ctl.admin.SetUserEnabled(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("username"), *request.Enabled)
+	respondToAdminAction(c, found, err)
+}
+
+func (ctl *AdminController) ForcePasswordReset(c *gin.Context) {
+	found, err := ctl.admin.ForcePasswordReset(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("username"))
+	respondToAdminAction(c, found, err)
+}
+
+func (ctl *AdminController) GrantAuthority(c *gin.Context) {
+	if err := ctl.admin.ValidateAuthority(c.Param("authority")); err != nil {
+		c.Error(err)
+		return
+	}
+	found, err := ctl.admin.GrantAuthority(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("username"), c.Param("authority"))
+	respondToAdminAction(c, found, err)
+}
+
+func (ctl *AdminController) RevokeAuthority(c *gin.Context) {
+	found, err := ctl.admin.RevokeAuthority(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("username"), c.Param("authority"))
+	respondToAdminAction(c, found, err)
+}
+
+func (ctl *AdminController) DeleteUser(c *gin.Context) {
+	found, err := ctl.admin.DeleteUser(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("username"))
+	respondToAdminAction(c, found, err)
+}
+
+func respondToAdminAction(c *gin.Context, found bool, err error) {
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !found {
+		c.Error(apperror.NotFound("user not found: %v", c.Param("username")))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
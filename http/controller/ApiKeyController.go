@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+type ApiKeyController struct {
+	apiKeys *service.ApiKeyService
+}
+
+func NewApiKeyController(apiKeys *service.ApiKeyService) *ApiKeyController {
+	return &ApiKeyController{apiKeys: apiKeys}
+}
+
+func (ctl *ApiKeyController) CreateApiKey(c *gin.Context) {
+	var request dto.ApiKeyRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if err := ctl.apiKeys.ValidateApiKeyRequest(request); err != nil {
+		c.Error(err)
+		return
+	}
+	apiKey, err := ctl.apiKeys.CreateApiKey(c.Request.Context(), middleware.GetPrincipal(c).Username, request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+func (ctl *ApiKeyController) GetApiKeys(c *gin.Context) {
+	apiKeys, err := ctl.apiKeys.GetApiKeys(c.Request.Context(), middleware.GetPrincipal(c).Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": apiKeys})
+}
+
+func (ctl *ApiKeyController) RevokeApiKey(c *gin.Context) {
+	found, err := ctl.apiKeys.RevokeApiKey(c.Request.Context(), middleware.GetPrincipal(c).Username, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !found {
+		c.Error(apperror.NotFound("api key not found: %v", c.Param("id")))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
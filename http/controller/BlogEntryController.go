@@ -1,60 +1,60 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 
+	"github.com/skyrenx/blog-api-go/http/apperror"
 	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/middleware"
 	"github.com/skyrenx/blog-api-go/http/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func GetBlogEntries(c *gin.Context) {
+type BlogEntryController struct {
+	blogEntries *service.BlogEntryService
+}
+
+func NewBlogEntryController(blogEntries *service.BlogEntryService) *BlogEntryController {
+	return &BlogEntryController{blogEntries: blogEntries}
+}
+
+func (ctl *BlogEntryController) GetBlogEntries(c *gin.Context) {
 	pageNumber, _ := strconv.Atoi(c.DefaultQuery("pageNumber", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "1"))
-	blogEntries, totalPages, err := service.GetBlogEntries(pageNumber, pageSize)
+	blogEntries, totalPages, err := ctl.blogEntries.GetBlogEntries(c.Request.Context(), pageNumber, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err) //TODO ?
+		c.Error(err)
 		return
 
 	}
 	c.JSON(http.StatusOK, gin.H{"blog_entries": blogEntries, "page_count": totalPages})
 }
 
-func GetBlogEntrySummaries(c *gin.Context) {
+func (ctl *BlogEntryController) GetBlogEntrySummaries(c *gin.Context) {
 	pageNumber, _ := strconv.Atoi(c.DefaultQuery("pageNumber", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "1"))
-	blogEntries, totalPages, err := service.GetBlogEntrySummaries(pageNumber, pageSize)
+	blogEntries, totalPages, err := ctl.blogEntries.GetBlogEntrySummaries(c.Request.Context(), pageNumber, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err) //TODO ?
+		c.Error(err)
 		return
 
 	}
 	c.JSON(http.StatusOK, gin.H{"blog_entry_summaries": blogEntries, "page_count": totalPages})
 }
 
-func GetBlogEntryById(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
+func (ctl *BlogEntryController) GetBlogEntryById(c *gin.Context) {
+	// Accepts the integer IDs of old entries as well as UUIDs.
+	id, err := entities.ParseBlogEntryID(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		c.Error(apperror.Validation("invalid ID: %v", c.Param("id")))
 		return
 	}
-	blogEntry, err := service.GetBlogEntryById(id)
+	blogEntry, err := ctl.blogEntries.GetBlogEntryById(c.Request.Context(), id)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -62,22 +62,18 @@ func GetBlogEntryById(c *gin.Context) {
 	})
 }
 
-func CreateBlogEntry(c *gin.Context) {
-	// Declare a BlogEntry instance
-	var entry entities.BlogEntry
-
-	// Bind the JSON body to the BlogEntry struct
-	if err := c.ShouldBindJSON(&entry); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+func (ctl *BlogEntryController) CreateBlogEntry(c *gin.Context) {
+	// Bind the JSON body to the request, fields controlled by the server cannot be set
+	var request dto.BlogEntryRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	err := service.CreateBlogEntry(entry)
+	id, err := ctl.blogEntries.CreateBlogEntry(c.Request.Context(), middleware.GetPrincipal(c).Username, request)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+		c.Error(err)
 		return
 	}
+	c.Header("Location", "/BlogEntry/"+string(id))
+	c.JSON(http.StatusCreated, gin.H{"id": id})
 }
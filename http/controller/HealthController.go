@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+type HealthController struct {
+	health    *service.HealthService
+	buildInfo dto.BuildInfo
+}
+
+func NewHealthController(health *service.HealthService, buildInfo dto.BuildInfo) *HealthController {
+	return &HealthController{health: health, buildInfo: buildInfo}
+}
+
+// Healthz reports that the process is alive, without checking any dependency.
+func (ctl *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.Health{Status: dto.HEALTH_STATUS_OK})
+}
+
+// Readyz reports whether the dependencies work, with 503 if any of them fails.
+func (ctl *HealthController) Readyz(c *gin.Context) {
+	health := ctl.health.Readiness(c.Request.Context())
+	status := http.StatusOK
+	if health.Status != dto.HEALTH_STATUS_OK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+func (ctl *HealthController) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, ctl.buildInfo)
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+func TestHealthWithFailingDependency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	health := service.NewHealthService(
+		service.HealthCheck{Name: "database", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		service.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error { return nil }},
+	)
+	ctl := NewHealthController(health, dto.BuildInfo{})
+	router := gin.New()
+	router.GET("/healthz", ctl.Healthz)
+	router.GET("/readyz", ctl.Readyz)
+
+	tests := []struct {
+		path       string
+		wantCode   int
+		wantStatus string
+	}{
+		{path: "/healthz", wantCode: http.StatusOK, wantStatus: dto.HEALTH_STATUS_OK},
+		{path: "/readyz", wantCode: http.StatusServiceUnavailable, wantStatus: dto.HEALTH_STATUS_FAIL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			var got dto.Health
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body is not a health: %v: %s", err, rec.Body)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+const (
+	OIDC_FLOW_COOKIE      = "oidc_flow"
+	OIDC_FLOW_COOKIE_PATH = "/User/oidc"
+)
+
+type OidcController struct {
+	oidc *service.OidcService
+}
+
+func NewOidcController(oidc *service.OidcService) *OidcController {
+	return &OidcController{oidc: oidc}
+}
+
+func (ctl *OidcController) OidcLogin(c *gin.Context) {
+	if !ctl.oidc.OidcEnabled() {
+		c.Error(apperror.NotFound("single sign on is not configured"))
+		return
+	}
+	redirectURL, signedFlow, err := ctl.oidc.StartOidcLogin(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	redirectToProvider(c, redirectURL, signedFlow)
+}
+
+// OidcLink signs in at the provider like OidcLogin, but links the identity to the signed in user.
+func (ctl *OidcController) OidcLink(c *gin.Context) {
+	if !ctl.oidc.OidcEnabled() {
+		c.Error(apperror.NotFound("single sign on is not configured"))
+		return
+	}
+	redirectURL, signedFlow, err := ctl.oidc.StartOidcLink(c.Request.Context(), middleware.GetPrincipal(c).Username)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	redirectToProvider(c, redirectURL, signedFlow)
+}
+
+// redirectToProvider keeps the signed flow state in a cookie that only the callback receives.
+func redirectToProvider(c *gin.Context, redirectURL string, signedFlow string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(OIDC_FLOW_COOKIE, signedFlow, int(service.OIDC_FLOW_EXPIRATION_TIME.Seconds()),
+		OIDC_FLOW_COOKIE_PATH, "", true, true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+func (ctl *OidcController) OidcCallback(c *gin.Context) {
+	if !ctl.oidc.OidcEnabled() {
+		c.Error(apperror.NotFound("single sign on is not configured"))
+		return
+	}
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.Error(apperror.Unauthorized("sign in was rejected: %v", errorCode))
+		return
+	}
+	signedFlow, err := c.Cookie(OIDC_FLOW_COOKIE)
+	if err != nil {
+		c.Error(apperror.Validation("sign in was not started"))
+		return
+	}
+	// The flow state can only be used once.
+	c.SetCookie(OIDC_FLOW_COOKIE, "", -1, OIDC_FLOW_COOKIE_PATH, "", true, true)
+
+	token, err := ctl.oidc.FinishOidcLogin(c.Request.Context(), c.Query("code"), c.Query("state"), signedFlow)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Sign in failed", "error", err)
+		// Errors the user can act on, like a taken username, keep their status.
+		if apperror.KindOf(err) == 0 {
+			err = apperror.Unauthorized("sign in failed").Wrap(err)
+		}
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, token)
+}
@@ -1,59 +1,70 @@
 package controller
 
 import (
-	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
-	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
 	"github.com/skyrenx/blog-api-go/http/service"
 )
 
-func GetUserByUsername(c *gin.Context) {
+type UserController struct {
+	users *service.UserService
+}
+
+func NewUserController(users *service.UserService) *UserController {
+	return &UserController{users: users}
+}
+
+func (ctl *UserController) GetUserByUsername(c *gin.Context) {
 	username := c.Param("username")
-	r, err := service.GetUserByUsername(username)
+	r, err := ctl.users.GetUserByUsername(c.Request.Context(), username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"Error: ": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, *r)
 }
 
-func Register(c *gin.Context) {
-	var user entities.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+func (ctl *UserController) Register(c *gin.Context) {
+	var request dto.RegisterRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	err := service.Register(user)
+	if err := ctl.users.ValidatePassword(request.Username, request.Password); err != nil {
+		c.Error(err)
+		return
+	}
+	err := ctl.users.Register(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+		c.Error(err)
 		return
 	}
 	c.Status(http.StatusCreated)
 }
 
-func Login(c *gin.Context) {
-	var user entities.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to run handler: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+func (ctl *UserController) Login(c *gin.Context) {
+	var request dto.LoginRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	token, err := service.Login(user)
+	token, err := ctl.users.Login(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process the request",
-		})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusAccepted, token)
 }
+
+func (ctl *UserController) ChangePassword(c *gin.Context) {
+	var request dto.ChangePasswordRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	err := ctl.users.ChangePassword(c.Request.Context(), request)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
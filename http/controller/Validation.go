@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+)
+
+// The binding tags of the request DTOs are checked by the validator of gin,
+// which is extended here with the rules of this api.
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic("unexpected validator engine")
+	}
+	// Errors name the fields as the client sent them.
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return entities.USERNAME_PATTERN.MatchString(fl.Field().String())
+	})
+}
+
+// bindJSON binds the request body to obj. If the body is invalid it records
+// a validation error for the ErrorResponse middleware and returns false.
+func bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.Error(bindingError(err))
+		return false
+	}
+	return true
+}
+
+// bindingError converts an error of ShouldBindJSON into a VALIDATION error,
+// listing the invalid fields where they are known.
+func bindingError(err error) *apperror.Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]apperror.FieldError, len(validationErrors))
+		for i, fieldError := range validationErrors {
+			fields[i] = apperror.FieldError{Field: fieldPath(fieldError), Message: fieldErrorMessage(fieldError)}
+		}
+		return apperror.InvalidFields(fields...)
+	}
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return apperror.TooLarge("request body exceeds %v bytes", maxBytesError.Limit)
+	}
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return apperror.InvalidFields(apperror.FieldError{
+			Field:   typeError.Field,
+			Message: fmt.Sprintf("must be of type %v", typeError.Type.Kind()),
+		})
+	}
+	return apperror.Validation("invalid request body: %v", err)
+}
+
+// fieldPath names nested fields by their path from the request, e.g. "author.name" or "scopes[0]".
+// The namespace of the validator starts with the name of the request type, which is dropped.
+func fieldPath(fieldError validator.FieldError) string {
+	_, path, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+	return path
+}
+
+func fieldErrorMessage(fieldError validator.FieldError) string {
+	unit := "characters"
+	if kind := fieldError.Kind(); kind == reflect.Slice || kind == reflect.Map {
+		unit = "items"
+	}
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must have at least %v %v", fieldError.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must have at most %v %v", fieldError.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("must be one of %v", fieldError.Param())
+	case "username":
+		return "must start with a letter or digit and may only contain letters, digits, '.', '_', '-' and '@'"
+	default:
+		return fmt.Sprintf("failed the %v rule", fieldError.Tag())
+	}
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+)
+
+type testAuthor struct {
+	Name string `json:"name" binding:"required,max=5"`
+}
+
+type testRequest struct {
+	Title  string      `json:"title" binding:"required,max=10"`
+	Author *testAuthor `json:"author" binding:"omitempty"`
+	Tags   []string    `json:"tags" binding:"omitempty,max=2,dive,max=3"`
+	Count  int         `json:"count"`
+}
+
+// bind posts body to a handler binding a testRequest and returns the problem of the response,
+// or nil if the body was bound.
+func bind(t *testing.T, body string) *dto.Problem {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorResponse())
+	router.POST("/test", func(c *gin.Context) {
+		var request testRequest
+		if bindJSON(c, &request) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body)))
+	if rec.Code == http.StatusNoContent {
+		return nil
+	}
+	var problem dto.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body is not a problem: %v: %s", err, rec.Body)
+	}
+	return &problem
+}
+
+func TestBindJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields []apperror.FieldError
+		// Prefix of the detail, only checked if there are no fields.
+		wantDetail string
+	}{
+		{
+			name: "valid",
+			body: `{"title": "Hello", "author": {"name": "ann"}, "tags": ["go"]}`,
+		},
+		{
+			name:       "required field",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "title", Message: "is required"}},
+		},
+		{
+			name:       "too long",
+			body:       `{"title": "Hello, world!"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "title", Message: "must have at most 10 characters"}},
+		},
+		{
+			name:       "nested field",
+			body:       `{"title": "Hello", "author": {"name": ""}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "author.name", Message: "is required"}},
+		},
+		{
+			name:       "too many items",
+			body:       `{"title": "Hello", "tags": ["a", "b", "c"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "tags", Message: "must have at most 2 items"}},
+		},
+		{
+			name:       "item of a list",
+			body:       `{"title": "Hello", "tags": ["go", "rust"]}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "tags[1]", Message: "must have at most 3 characters"}},
+		},
+		{
+			name:       "several fields",
+			body:       `{"author": {"name": "Bartholomew"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{
+				{Field: "title", Message: "is required"},
+				{Field: "author.name", Message: "must have at most 5 characters"},
+			},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"title": "Hello", "count": "three"}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "count", Message: "must be of type int"}},
+		},
+		{
+			name:       "wrong type of a nested field",
+			body:       `{"title": "Hello", "author": {"name": 5}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: []apperror.FieldError{{Field: "author.name", Message: "must be of type string"}},
+		},
+		{
+			name:       "malformed JSON",
+			body:       `{"title": `,
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request body: ",
+		},
+		{
+			name:       "empty body",
+			body:       ``,
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request body: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := bind(t, tt.body)
+			if tt.wantStatus == 0 {
+				if problem != nil {
+					t.Fatalf("problem = %+v, want the body to be bound", problem)
+				}
+				return
+			}
+			if problem == nil {
+				t.Fatalf("body was bound, want status %v", tt.wantStatus)
+			}
+			if problem.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", problem.Status, tt.wantStatus)
+			}
+			if !slices.Equal(problem.Errors, tt.wantFields) {
+				t.Errorf("errors = %+v, want %+v", problem.Errors, tt.wantFields)
+			}
+			if tt.wantFields == nil && !strings.HasPrefix(problem.Detail, tt.wantDetail) {
+				t.Errorf("detail = %q, want it to start with %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestBindJSONUsername(t *testing.T) {
+	tests := []struct {
+		username  string
+		wantValid bool
+	}{
+		{username: "alice", wantValid: true},
+		{username: "alice.smith@example.com", wantValid: true},
+		{username: "al", wantValid: false},
+		{username: ".alice", wantValid: false},
+		{username: "alice smith", wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			body, _ := json.Marshal(dto.RegisterRequest{Username: tt.username, Password: "secret-password"})
+			c.Request = httptest.NewRequest(http.MethodPost, "/User/register", strings.NewReader(string(body)))
+			var request dto.RegisterRequest
+			if valid := bindJSON(c, &request); valid != tt.wantValid {
+				t.Errorf("bindJSON = %v, want %v, errors: %v", valid, tt.wantValid, c.Errors)
+			}
+		})
+	}
+}
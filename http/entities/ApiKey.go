@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+const (
+	// Scope required to create blog entries.
+	SCOPE_BLOG_ENTRIES_WRITE = "blog_entries:write"
+)
+
+// Scopes that can be granted to an api key.
+var ApiKeyScopes = []string{SCOPE_BLOG_ENTRIES_WRITE}
+
+// ApiKey represents a row in the api_keys table.
+type ApiKey struct {
+	ID         string     `json:"id" db:"id"`
+	Username   string     `json:"username" db:"username"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     string     `json:"scopes" db:"scopes"` // comma separated
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+}
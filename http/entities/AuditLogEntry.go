@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+const (
+	AUDIT_ACTION_USER_EXPORT         = "user.export"
+	AUDIT_ACTION_USER_DELETE         = "user.delete"
+	AUDIT_ACTION_USER_ANONYMIZE      = "user.anonymize"
+	AUDIT_ACTION_USER_ENABLE         = "user.enable"
+	AUDIT_ACTION_USER_DISABLE        = "user.disable"
+	AUDIT_ACTION_USER_PASSWORD_RESET = "user.password_reset"
+	AUDIT_ACTION_AUTHORITY_GRANT     = "authority.grant"
+	AUDIT_ACTION_AUTHORITY_REVOKE    = "authority.revoke"
+)
+
+// AuditLogEntry represents a row in the audit_log table.
+type AuditLogEntry struct {
+	ID        string    `json:"id" db:"id"`
+	Actor     string    `json:"actor" db:"actor"`
+	Action    string    `json:"action" db:"action"`
+	Target    string    `json:"target" db:"target"`
+	Details   string    `json:"details" db:"details"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
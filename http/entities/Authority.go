@@ -0,0 +1,12 @@
+package entities
+
+const (
+	// Authority required for the account administration endpoints.
+	AUTHORITY_ADMIN = "ROLE_ADMIN"
+)
+
+// Authority represents a row in the authorities table.
+type Authority struct {
+	Username  string `json:"username" db:"username"`
+	Authority string `json:"authority" db:"authority"`
+}
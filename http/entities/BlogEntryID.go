@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ID strategies for new blog entries, selected with BLOG_ENTRY_ID_STRATEGY.
+const (
+	// Integers from the blog_entry_sequence table. Every insert updates the same row,
+	// so concurrent writers conflict.
+	ID_STRATEGY_SEQUENCE = "sequence"
+	// Time ordered UUIDs generated by the api, writers never conflict.
+	ID_STRATEGY_UUIDV7 = "uuidv7"
+)
+
+// BlogEntryID identifies a blog entry. It is either a legacy integer, stored as its decimal string,
+// or a UUID. Legacy IDs are written to JSON as numbers so existing clients keep working,
+// and both forms are accepted as JSON numbers or strings.
+type BlogEntryID string
+
+// NewBlogEntryID generates a UUIDv7 ID.
+func NewBlogEntryID() (BlogEntryID, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate blog entry id: %w", err)
+	}
+	return BlogEntryID(id.String()), nil
+}
+
+// LegacyBlogEntryID converts an ID of the sequence strategy.
+func LegacyBlogEntryID(id int64) BlogEntryID {
+	return BlogEntryID(strconv.FormatInt(id, 10))
+}
+
+// ParseBlogEntryID parses a legacy integer or a UUID into its canonical form.
+// Every entry has a single ID, so signs and leading zeros are rejected rather than dropped.
+func ParseBlogEntryID(s string) (BlogEntryID, error) {
+	if id := BlogEntryID(s); id.IsLegacy() {
+		return id, nil
+	}
+	if id, err := uuid.Parse(s); err == nil && len(s) == 36 {
+		return BlogEntryID(id.String()), nil
+	}
+	return "", fmt.Errorf("invalid blog entry id: %v", s)
+}
+
+// IsLegacy reports whether the ID was created by the sequence strategy.
+func (id BlogEntryID) IsLegacy() bool {
+	_, ok := id.legacyInt()
+	return ok
+}
+
+// legacyInt returns the integer of a legacy ID. Only positive integers written as plain
+// decimal digits without leading zeros are legacy, ParseInt alone would also accept "+5" and "007".
+func (id BlogEntryID) legacyInt() (int64, bool) {
+	if id == "" || id[0] == '0' {
+		return 0, false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(string(id), 10, 64)
+	return n, err == nil
+}
+
+// MarshalJSON writes legacy IDs as the number they were parsed to, so the output is always valid JSON.
+func (id BlogEntryID) MarshalJSON() ([]byte, error) {
+	if n, ok := id.legacyInt(); ok {
+		return strconv.AppendInt(nil, n, 10), nil
+	}
+	return json.Marshal(string(id))
+}
+
+func (id *BlogEntryID) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	if s == "" {
+		*id = ""
+		return nil
+	}
+	parsed, err := ParseBlogEntryID(s)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
@@ -0,0 +1,102 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBlogEntryIDJSON(t *testing.T) {
+	uuidv7, err := NewBlogEntryID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		id       BlogEntryID
+		wantJSON string
+		// The ID read back from wantJSON, id if empty.
+		wantID           BlogEntryID
+		wantUnmarshalErr bool
+	}{
+		{name: "legacy integer", id: LegacyBlogEntryID(42), wantJSON: `42`},
+		{name: "largest legacy integer", id: LegacyBlogEntryID(9223372036854775807), wantJSON: `9223372036854775807`},
+		{name: "generated uuidv7", id: uuidv7, wantJSON: `"` + string(uuidv7) + `"`},
+		{name: "uuidv7", id: "01890a5d-ac96-774b-bcce-b302099a8057", wantJSON: `"01890a5d-ac96-774b-bcce-b302099a8057"`},
+		{name: "signed integer is not legacy", id: "+5", wantJSON: `"+5"`, wantUnmarshalErr: true},
+		{name: "negative integer is not legacy", id: "-5", wantJSON: `"-5"`, wantUnmarshalErr: true},
+		{name: "leading zeros are not legacy", id: "007", wantJSON: `"007"`, wantUnmarshalErr: true},
+		{name: "zero is not legacy", id: "0", wantJSON: `"0"`, wantUnmarshalErr: true},
+		{name: "empty", id: "", wantJSON: `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.id)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Fatalf("Marshal = %s, want %s", data, tt.wantJSON)
+			}
+			if !json.Valid(data) {
+				t.Fatalf("Marshal wrote invalid JSON: %s", data)
+			}
+
+			var id BlogEntryID
+			err = json.Unmarshal(data, &id)
+			if tt.wantUnmarshalErr {
+				if err == nil {
+					t.Fatalf("Unmarshal of %s succeeded with %q", data, id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			wantID := tt.wantID
+			if wantID == "" {
+				wantID = tt.id
+			}
+			if id != wantID {
+				t.Errorf("Unmarshal = %q, want %q", id, wantID)
+			}
+		})
+	}
+}
+
+func TestBlogEntryIDUnmarshal(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    BlogEntryID
+		wantErr bool
+	}{
+		{json: `42`, want: "42"},
+		{json: `"42"`, want: "42"},
+		{json: `"01890A5D-AC96-774B-BCCE-B302099A8057"`, want: "01890a5d-ac96-774b-bcce-b302099a8057"},
+		{json: `null`, want: ""},
+		{json: `0`, wantErr: true},
+		{json: `"+5"`, wantErr: true},
+		{json: `"007"`, wantErr: true},
+		{json: `" 5"`, wantErr: true},
+		{json: `"9223372036854775808"`, wantErr: true},
+		{json: `"not-an-id"`, wantErr: true},
+		{json: `"{01890a5d-ac96-774b-bcce-b302099a8057}"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var id BlogEntryID
+			err := json.Unmarshal([]byte(tt.json), &id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal succeeded with %q", id)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if id != tt.want {
+				t.Errorf("Unmarshal = %q, want %q", id, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,6 @@ package entities
 import "github.com/golang-jwt/jwt/v5"
 
 type Claims struct {
-    Username string `json:"username"`
-    jwt.RegisteredClaims
+	Username string `json:"username"`
+	jwt.RegisteredClaims
 }
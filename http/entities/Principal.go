@@ -0,0 +1,31 @@
+package entities
+
+import "slices"
+
+const (
+	AUTH_METHOD_JWT     = "jwt"
+	AUTH_METHOD_API_KEY = "apikey"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Username   string
+	AuthMethod string
+	// ID of the api key the caller authenticated with, if AuthMethod is AUTH_METHOD_API_KEY.
+	ApiKeyID string
+	// Scopes granted to the caller. Nil means unrestricted (interactive logins).
+	Scopes []string
+	// Authorities of the user, loaded on every request.
+	Authorities []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	return slices.Contains(p.Scopes, scope)
+}
+
+func (p *Principal) HasAuthority(authority string) bool {
+	return slices.Contains(p.Authorities, authority)
+}
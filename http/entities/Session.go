@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+// Session represents a row in the sessions table, one for every issued token.
+// Tokens are verified by their signature, the row only records that the token was issued.
+type Session struct {
+	// The "jti" claim of the token.
+	ID       string `json:"id" db:"id"`
+	Username string `json:"username" db:"username"`
+	// "password" or "oidc".
+	LoginMethod string    `json:"login_method" db:"login_method"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+}
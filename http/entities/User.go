@@ -1,9 +1,31 @@
 package entities
 
-//db table is "users"
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	MIN_USERNAME_LENGTH = 3
+	MAX_USERNAME_LENGTH = 50
+)
+
+// Letters, digits and . _ - @, starting with a letter or digit.
+var USERNAME_PATTERN = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+
+// db table is "users"
 type User struct {
 	Username string `json:"username" db:"username"`
 	Password string `json:"password" db:"password"` // TODO ensure password is not retrievable.
 	// Malicious users with access to encrypted passwords can attemp to decrypt the password offline.
-	Enabled  bool   `json:"enabled" db:"enabled"`
+	Enabled               bool `json:"enabled" db:"enabled"`
+	PasswordResetRequired bool `json:"-" db:"password_reset_required"`
+	// Tokens issued before are rejected. Nil if the password was never changed.
+	PasswordChangedAt *time.Time `json:"-" db:"password_changed_at"`
+}
+
+// ValidUsername reports whether username follows the same rules as a registration.
+func ValidUsername(username string) bool {
+	return len(username) >= MIN_USERNAME_LENGTH && len(username) <= MAX_USERNAME_LENGTH &&
+		USERNAME_PATTERN.MatchString(username)
 }
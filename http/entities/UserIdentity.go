@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// UserIdentity represents a row in the user_identities table.
+type UserIdentity struct {
+	Issuer    string    `json:"issuer" db:"issuer"`
+	Subject   string    `json:"subject" db:"subject"`
+	Username  string    `json:"username" db:"username"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
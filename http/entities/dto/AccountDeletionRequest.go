@@ -0,0 +1,21 @@
+package dto
+
+const (
+	// Remove the user row.
+	ACCOUNT_DELETION_MODE_DELETE = "delete"
+	// Keep a disabled user row under a random name that cannot be traced back to the user.
+	ACCOUNT_DELETION_MODE_ANONYMIZE = "anonymize"
+
+	ENTRIES_POLICY_DELETE = "delete"
+	// Hand the entries to the existing user named by ReassignTo.
+	ENTRIES_POLICY_REASSIGN  = "reassign"
+	ENTRIES_POLICY_ANONYMIZE = "anonymize"
+)
+
+type AccountDeletionRequest struct {
+	Mode string `json:"mode"`
+	// What happens to the blog entries authored by the user.
+	Entries string `json:"entries"`
+	// Author of the entries when Entries is "reassign".
+	ReassignTo string `json:"reassign_to" binding:"max=50"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+import "time"
+
+// The scopes and the expiration are checked by the service.
+type ApiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
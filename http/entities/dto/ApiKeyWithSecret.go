@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/skyrenx/blog-api-go/http/entities"
+
+// ApiKeyWithSecret is returned once when an api key is created.
+// The plain key cannot be retrieved afterwards.
+type ApiKeyWithSecret struct {
+	entities.ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+// BlogEntryRequest is the part of a blog entry a client may set.
+// The ID and the timestamps are assigned by the server, the author is the authenticated user.
+type BlogEntryRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+	// Limited to keep a single entry well below the Lambda payload limit of 6 MB.
+	Content   string `json:"content" binding:"required,max=100000"`
+	Published bool   `json:"published"`
+}
@@ -1,11 +1,15 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/entities"
+)
 
 // BlogEntry represents a row in the blog_entries table.
 type BlogEntrySummary struct {
-	ID        int       `json:"id" db:"id"`
-	Title     string    `json:"title" db:"title"`
-	Author    string    `json:"author" db:"author"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        entities.BlogEntryID `json:"id" db:"id"`
+	Title     string               `json:"title" db:"title"`
+	Author    string               `json:"author" db:"author"`
+	CreatedAt time.Time            `json:"created_at" db:"created_at"`
 }
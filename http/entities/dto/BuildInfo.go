@@ -0,0 +1,8 @@
+package dto
+
+// BuildInfo identifies the running build, it is the body of /version.
+type BuildInfo struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type ChangePasswordRequest struct {
+	Username        string `json:"username" binding:"required,max=50"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
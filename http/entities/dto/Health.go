@@ -0,0 +1,19 @@
+package dto
+
+const (
+	HEALTH_STATUS_OK   = "ok"
+	HEALTH_STATUS_FAIL = "fail"
+)
+
+// Health is the body of /healthz and /readyz, Checks lists the result of every dependency.
+type Health struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+// LoginRequest does not check the username charset, accounts provisioned through
+// single sign on may use characters a registration rejects.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required"`
+}
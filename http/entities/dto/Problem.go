@@ -0,0 +1,20 @@
+package dto
+
+import "github.com/skyrenx/blog-api-go/http/apperror"
+
+const (
+	PROBLEM_CONTENT_TYPE = "application/problem+json"
+	// The problem has no type of its own, its title is the status text.
+	PROBLEM_TYPE_BLANK = "about:blank"
+)
+
+// Problem is the body of every error response, as defined by RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extension member with the invalid fields of a 400.
+	Errors []apperror.FieldError `json:"errors,omitempty"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type RegisterRequest struct {
+	// Usernames are stored in VARCHAR(50) columns.
+	Username string `json:"username" binding:"required,min=3,max=50,username"`
+	// Checked against the password policy by the service.
+	Password string `json:"password" binding:"required"`
+}
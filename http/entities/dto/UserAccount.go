@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// UserAccount is a user as seen by administrators.
+type UserAccount struct {
+	Username              string `json:"username" db:"username"`
+	Enabled               bool   `json:"enabled" db:"enabled"`
+	PasswordResetRequired bool   `json:"password_reset_required" db:"password_reset_required"`
+	// Tokens issued before are rejected. Nil if the password was never changed.
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" db:"password_changed_at"`
+	Authorities       []string   `json:"authorities" db:"-"`
+}
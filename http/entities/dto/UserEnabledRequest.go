@@ -0,0 +1,5 @@
+package dto
+
+type UserEnabledRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/entities"
+)
+
+// UserExport is the personal data archive of a user.
+type UserExport struct {
+	ExportedAt  time.Time            `json:"exported_at"`
+	Profile     UserAccount          `json:"profile"`
+	BlogEntries []entities.BlogEntry `json:"blog_entries"`
+	// Always empty, blog entries cannot be commented on yet.
+	Comments   []any                   `json:"comments"`
+	Sessions   []entities.Session      `json:"sessions"`
+	ApiKeys    []entities.ApiKey       `json:"api_keys"`
+	Identities []entities.UserIdentity `json:"identities"`
+}
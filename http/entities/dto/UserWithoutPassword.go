@@ -3,5 +3,5 @@ package dto
 type UserWithoutPassword struct {
 	Username string `json:"username" db:"username"`
 	// Malicious users with access to encrypted passwords can attempt to decrypt the password offline.
-	Enabled  bool   `json:"enabled" db:"enabled"`
-}
\ No newline at end of file
+	Enabled bool `json:"enabled" db:"enabled"`
+}
@@ -0,0 +1,101 @@
+// Package logging configures the JSON logger of log/slog used by the whole api.
+//
+// Records logged with a request context carry the IDs of the request and of its trace span,
+// and attributes that look like credentials are redacted.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const REDACTED = "[REDACTED]"
+
+// Attributes whose key contains one of these are never written.
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "apikey", "credential"}
+
+type contextKey struct{}
+
+// RequestIDs identify a request in the logs of the api and of AWS.
+type RequestIDs struct {
+	RequestID        string // X-Request-ID, propagated from the client or assigned by the api
+	LambdaRequestID  string // Request ID of the Lambda invocation, if any
+	GatewayRequestID string // Request ID of API Gateway, if any
+}
+
+// WithRequestIDs returns a context whose log records carry ids.
+func WithRequestIDs(ctx context.Context, ids RequestIDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// GetRequestIDs returns the IDs of the request of ctx.
+func GetRequestIDs(ctx context.Context) (RequestIDs, bool) {
+	ids, ok := ctx.Value(contextKey{}).(RequestIDs)
+	return ids, ok
+}
+
+// Setup makes a JSON logger writing to stdout at level the default of slog and of the log package.
+func Setup(level slog.Level) {
+	slog.SetDefault(New(os.Stdout, level))
+}
+
+// New creates a JSON logger that adds the request and trace IDs of the context and redacts credentials.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redact})
+	return slog.New(requestIDHandler{handler})
+}
+
+// IsSensitive reports whether values under key must not be logged.
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact is called for the attributes within groups too, but not for the groups themselves,
+// so every attribute of a group with a sensitive name is redacted.
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) || slices.ContainsFunc(groups, IsSensitive) {
+		return slog.String(attr.Key, REDACTED)
+	}
+	return attr
+}
+
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ids, ok := GetRequestIDs(ctx); ok {
+		record.AddAttrs(slog.String("request_id", ids.RequestID))
+		if ids.LambdaRequestID != "" {
+			record.AddAttrs(slog.String("lambda_request_id", ids.LambdaRequestID))
+		}
+		if ids.GatewayRequestID != "" {
+			record.AddAttrs(slog.String("gateway_request_id", ids.GatewayRequestID))
+		}
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
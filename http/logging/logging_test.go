@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	const secret = "s3cr3t-value"
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+		// Path of the attribute in the JSON record and whether it must be redacted.
+		path       []string
+		wantRedact bool
+	}{
+		{
+			name: "password",
+			log:  func(logger *slog.Logger) { logger.Info("Login", "password", secret) },
+			path: []string{"password"}, wantRedact: true,
+		},
+		{
+			name: "key is case insensitive",
+			log:  func(logger *slog.Logger) { logger.Info("Login", "NewPassword", secret) },
+			path: []string{"NewPassword"}, wantRedact: true,
+		},
+		{
+			name: "authorization header",
+			log: func(logger *slog.Logger) {
+				logger.Info("Request", slog.Group("headers", slog.String("Authorization", "Bearer "+secret)))
+			},
+			path: []string{"headers", "Authorization"}, wantRedact: true,
+		},
+		{
+			name: "token",
+			log:  func(logger *slog.Logger) { logger.Info("Login", "id_token", secret) },
+			path: []string{"id_token"}, wantRedact: true,
+		},
+		{
+			name: "api key added with With",
+			log:  func(logger *slog.Logger) { logger.With("api_key", secret).Info("Request") },
+			path: []string{"api_key"}, wantRedact: true,
+		},
+		{
+			name: "cookie within WithGroup",
+			log:  func(logger *slog.Logger) { logger.WithGroup("request").Info("Request", "cookie", "oidc_flow="+secret) },
+			path: []string{"request", "cookie"}, wantRedact: true,
+		},
+		{
+			name: "attribute of a sensitive group",
+			log: func(logger *slog.Logger) {
+				logger.Info("Resolve", slog.Group("credentials", slog.String("value", secret)))
+			},
+			path: []string{"credentials", "value"}, wantRedact: true,
+		},
+		{
+			name: "other attributes are kept",
+			log:  func(logger *slog.Logger) { logger.Info("Login", "user", secret) },
+			path: []string{"user"}, wantRedact: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(New(&buf, slog.LevelInfo))
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("record is not JSON: %v: %s", err, buf.String())
+			}
+			var value any = record
+			for _, key := range tt.path {
+				group, ok := value.(map[string]any)
+				if !ok {
+					t.Fatalf("%v is not a group in %s", key, buf.String())
+				}
+				value = group[key]
+			}
+			want := secret
+			if tt.wantRedact {
+				want = REDACTED
+				if strings.Contains(buf.String(), secret) {
+					t.Errorf("record contains the secret: %s", buf.String())
+				}
+			}
+			if value != want {
+				t.Errorf("%v = %v, want %v", strings.Join(tt.path, "."), value, want)
+			}
+		})
+	}
+}
+
+func TestRequestIDs(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithRequestIDs(context.Background(), RequestIDs{RequestID: "req-1", LambdaRequestID: "lambda-1"})
+	New(&buf, slog.LevelInfo).InfoContext(ctx, "Request handled")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["request_id"] != "req-1" || record["lambda_request_id"] != "lambda-1" {
+		t.Errorf("record = %v, want the request IDs", record)
+	}
+	if _, ok := record["gateway_request_id"]; ok {
+		t.Errorf("record = %v, want no empty gateway_request_id", record)
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// CloudWatch accepts at most 100 values of a metric in one EMF line.
+const EMF_MAX_VALUES = 100
+
+// EnableEMF starts recording what FlushEMF writes, with the metrics in namespace.
+func EnableEMF(namespace string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.emfNamespace = namespace
+}
+
+// FlushEMF writes one EMF line for every series recorded since the last flush and starts over,
+// e.g. at the end of every Lambda invocation. Counters are written as their increment,
+// histograms as their observations. Does nothing if EMF is not enabled.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+func FlushEMF(w io.Writer) error {
+	registry.mu.Lock()
+	namespace := registry.emfNamespace
+	metrics := append([]metric(nil), registry.metrics...)
+	registry.mu.Unlock()
+	if namespace == "" {
+		return nil
+	}
+	e := &emfWriter{encoder: json.NewEncoder(w), namespace: namespace, timestamp: time.Now().UnixMilli()}
+	for _, m := range metrics {
+		m.flushEMF(e)
+	}
+	return e.err
+}
+
+type emfWriter struct {
+	encoder   *json.Encoder
+	namespace string
+	timestamp int64
+	err       error
+}
+
+type emfMetadata struct {
+	Timestamp         int64                `json:"Timestamp"`
+	CloudWatchMetrics []emfMetricDirective `json:"CloudWatchMetrics"`
+}
+
+type emfMetricDirective struct {
+	Namespace  string          `json:"Namespace"`
+	Dimensions [][]string      `json:"Dimensions"`
+	Metrics    []emfDefinition `json:"Metrics"`
+}
+
+type emfDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// write encodes the values of a series, the labels become the dimensions.
+func (e *emfWriter) write(v *vec, s *series, unit string, values []float64) {
+	if e.err != nil {
+		return
+	}
+	line := map[string]any{
+		"_aws": emfMetadata{
+			Timestamp: e.timestamp,
+			CloudWatchMetrics: []emfMetricDirective{{
+				Namespace:  e.namespace,
+				Dimensions: [][]string{append([]string{}, v.labelNames...)},
+				Metrics:    []emfDefinition{{Name: v.name, Unit: unit}},
+			}},
+		},
+	}
+	for i, name := range v.labelNames {
+		line[name] = s.labelValues[i]
+	}
+	if len(values) == 1 {
+		line[v.name] = values[0]
+	} else {
+		line[v.name] = values
+	}
+	e.err = e.encoder.Encode(line)
+}
+
+func (c *Counter) flushEMF(e *emfWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.sorted() {
+		if len(s.pending) > 0 {
+			e.write(&c.vec, s, "Count", s.pending)
+			s.pending = nil
+		}
+	}
+}
+
+func (h *Histogram) flushEMF(e *emfWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.sorted() {
+		for start := 0; start < len(s.pending); start += EMF_MAX_VALUES {
+			e.write(&h.vec, s, "Seconds", s.pending[start:min(start+EMF_MAX_VALUES, len(s.pending))])
+		}
+		s.pending = nil
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// flushLines flushes EMF and returns the decoded lines.
+func flushLines(t *testing.T) []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := FlushEMF(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]any
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("line is not JSON: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func enableEMF(t *testing.T) {
+	EnableEMF("Blog")
+	t.Cleanup(func() { EnableEMF("") })
+}
+
+func TestFlushEMFCounter(t *testing.T) {
+	enableEMF(t)
+	c := NewCounter("test_emf_requests_total", "Requests.", "route")
+	c.Inc("/a")
+	c.Add(2, "/a")
+
+	lines := flushLines(t)
+	if len(lines) != 1 {
+		t.Fatalf("lines = %v, want 1", lines)
+	}
+	line := lines[0]
+	if line["test_emf_requests_total"] != 3.0 || line["route"] != "/a" {
+		t.Errorf("line = %v, want the increment 3 of /a", line)
+	}
+	directive := line["_aws"].(map[string]any)["CloudWatchMetrics"].([]any)[0].(map[string]any)
+	want := map[string]any{
+		"Namespace":  "Blog",
+		"Dimensions": []any{[]any{"route"}},
+		"Metrics":    []any{map[string]any{"Name": "test_emf_requests_total", "Unit": "Count"}},
+	}
+	if !reflect.DeepEqual(directive, want) {
+		t.Errorf("directive = %v, want %v", directive, want)
+	}
+
+	if lines := flushLines(t); len(lines) != 0 {
+		t.Errorf("second flush = %v, want nothing", lines)
+	}
+	// The total of the series is not reset by a flush.
+	if got := c.Value("/a"); got != 3 {
+		t.Errorf("Value = %v, want 3", got)
+	}
+}
+
+func TestFlushEMFHistogramSplitsValues(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations int
+		// Number of values in every line, a single value is written as a number.
+		wantLines []int
+	}{
+		{name: "single value", observations: 1, wantLines: []int{1}},
+		{name: "maximum values", observations: EMF_MAX_VALUES, wantLines: []int{EMF_MAX_VALUES}},
+		{name: "one more than the maximum", observations: EMF_MAX_VALUES + 1, wantLines: []int{EMF_MAX_VALUES, 1}},
+		{name: "several lines", observations: 2*EMF_MAX_VALUES + 50, wantLines: []int{EMF_MAX_VALUES, EMF_MAX_VALUES, 50}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enableEMF(t)
+			h := NewHistogram(fmt.Sprintf("test_emf_duration_%v_seconds", i), "Durations.", DEFAULT_BUCKETS)
+			for n := range tt.observations {
+				h.Observe(float64(n))
+			}
+
+			lines := flushLines(t)
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("lines = %v, want %v", len(lines), len(tt.wantLines))
+			}
+			next := 0.0
+			for j, line := range lines {
+				var values []any
+				switch value := line[h.name].(type) {
+				case float64:
+					values = []any{value}
+				case []any:
+					values = value
+				}
+				if len(values) != tt.wantLines[j] {
+					t.Errorf("line %v has %v values, want %v", j, len(values), tt.wantLines[j])
+				}
+				if tt.wantLines[j] == 1 && reflect.TypeOf(line[h.name]).Kind() != reflect.Float64 {
+					t.Errorf("line %v = %v, want a single number", j, line[h.name])
+				}
+				// Every observation is written once, in the order it was made.
+				for _, value := range values {
+					if value != next {
+						t.Fatalf("value = %v, want %v", value, next)
+					}
+					next++
+				}
+			}
+		})
+	}
+}
+
+func TestFlushEMFDisabled(t *testing.T) {
+	c := NewCounter("test_emf_disabled_total", "Recorded while EMF is disabled.")
+	c.Inc()
+	var buf bytes.Buffer
+	if err := FlushEMF(&buf); err != nil || buf.Len() != 0 {
+		t.Errorf("FlushEMF wrote %q, %v, want nothing", buf.String(), err)
+	}
+	// Observations made while disabled are not written once it is enabled.
+	enableEMF(t)
+	if lines := flushLines(t); len(lines) != 0 {
+		t.Errorf("lines = %v, want nothing", lines)
+	}
+}
@@ -0,0 +1,246 @@
+// Package metrics records counters and histograms of the api.
+//
+// The standalone server exposes them for Prometheus with Handler. In Lambda, where nothing
+// can scrape the process, FlushEMF writes what was recorded during an invocation as
+// CloudWatch Embedded Metric Format log lines, which CloudWatch turns into metrics.
+//
+// Metrics are package variables of the packages that record them, created with
+// NewCounter or NewHistogram. Label values should come from a small, fixed set.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default buckets of latency histograms, in seconds.
+var DEFAULT_BUCKETS = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+	// EMF namespace, recording for EMF is disabled while it is empty.
+	emfNamespace string
+}
+
+type metric interface {
+	writePrometheus(b *strings.Builder)
+	flushEMF(e *emfWriter)
+}
+
+func register(name string, m metric) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.names == nil {
+		registry.names = make(map[string]bool)
+	}
+	if registry.names[name] {
+		panic(fmt.Sprintf("metric %v is registered twice", name))
+	}
+	registry.names[name] = true
+	registry.metrics = append(registry.metrics, m)
+}
+
+func emfEnabled() bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	return registry.emfNamespace != ""
+}
+
+// series is the state of a metric for one combination of label values.
+type series struct {
+	labelValues []string
+	value       float64   // Counter total
+	counts      []uint64  // Histogram observations per bucket, not cumulative
+	sum         float64   // Histogram sum of observations
+	count       uint64    // Histogram number of observations
+	pending     []float64 // Observations, or the counter increment, since the last FlushEMF
+}
+
+// vec holds the series of a metric, keyed by their label values.
+type vec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %v has labels %v, got values %v", v.name, v.labelNames, labelValues))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series ordered by their label values, so the output is stable.
+func (v *vec) sorted() []*series {
+	sorted := make([]*series, 0, len(v.series))
+	for _, s := range v.series {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.Join(sorted[i].labelValues, "\xff") < strings.Join(sorted[j].labelValues, "\xff")
+	})
+	return sorted
+}
+
+// Counter is a total that only increases, e.g. of requests.
+type Counter struct {
+	vec
+}
+
+// NewCounter registers a counter, its name should end with _total.
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	c := &Counter{vec{name: name, help: help, labelNames: labelNames, series: make(map[string]*series)}}
+	register(name, c)
+	return c
+}
+
+// Inc adds 1 to the series of labelValues, which are given in the order of the label names.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(value float64, labelValues ...string) {
+	emf := emfEnabled()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.get(labelValues)
+	s.value += value
+	if emf {
+		if len(s.pending) == 0 {
+			s.pending = []float64{0}
+		}
+		s.pending[0] += value
+	}
+}
+
+// Value returns the total of the series of labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(labelValues).value
+}
+
+// Histogram counts observations, e.g. latencies, in buckets.
+type Histogram struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogram registers a histogram with the upper bounds of its buckets in increasing order.
+// Latencies are observed in seconds and their names end with _seconds.
+func NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	h := &Histogram{vec: vec{name: name, help: help, labelNames: labelNames, series: make(map[string]*series)}, buckets: buckets}
+	register(name, h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	emf := emfEnabled()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets)+1)
+	}
+	i := sort.SearchFloat64s(h.buckets, value)
+	s.counts[i]++
+	s.sum += value
+	s.count++
+	if emf {
+		s.pending = append(s.pending, value)
+	}
+}
+
+// ObserveSince observes the seconds passed since start.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Handler serves all metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, Prometheus())
+	})
+}
+
+// Prometheus returns all metrics in the Prometheus text format.
+func Prometheus() string {
+	registry.mu.Lock()
+	metrics := append([]metric(nil), registry.metrics...)
+	registry.mu.Unlock()
+	var b strings.Builder
+	for _, m := range metrics {
+		m.writePrometheus(&b)
+	}
+	return b.String()
+}
+
+func (c *Counter) writePrometheus(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(b, c.name, c.help, "counter")
+	for _, s := range c.sorted() {
+		fmt.Fprintf(b, "%v%v %v\n", c.name, formatLabels(c.labelNames, s.labelValues, "", ""), formatValue(s.value))
+	}
+}
+
+func (h *Histogram) writePrometheus(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(b, h.name, h.help, "histogram")
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, upperBound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%v_bucket%v %v\n", h.name, formatLabels(h.labelNames, s.labelValues, "le", formatValue(upperBound)), cumulative)
+		}
+		fmt.Fprintf(b, "%v_bucket%v %v\n", h.name, formatLabels(h.labelNames, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%v_sum%v %v\n", h.name, formatLabels(h.labelNames, s.labelValues, "", ""), formatValue(s.sum))
+		fmt.Fprintf(b, "%v_count%v %v\n", h.name, formatLabels(h.labelNames, s.labelValues, "", ""), s.count)
+	}
+}
+
+func writeHeader(b *strings.Builder, name string, help string, kind string) {
+	fmt.Fprintf(b, "# HELP %v %v\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(b, "# TYPE %v %v\n", name, kind)
+}
+
+// formatLabels returns {name="value",...}, extraName is appended if it is set, e.g. le of a bucket.
+func formatLabels(names []string, values []string, extraName string, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, name, escape.Replace(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, extraName, extraValue))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprint(value)
+}
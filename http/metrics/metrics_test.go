@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func prometheus(m metric) string {
+	var b strings.Builder
+	m.writePrometheus(&b)
+	return b.String()
+}
+
+func TestCounterPrometheus(t *testing.T) {
+	c := NewCounter("test_requests_total", "Requests.\nBy route.", "route", "status")
+	c.Inc("/b", "200")
+	c.Add(2, "/a", "500")
+	c.Inc("/a", "500")
+
+	want := `# HELP test_requests_total Requests.\nBy route.
+# TYPE test_requests_total counter
+test_requests_total{route="/a",status="500"} 3
+test_requests_total{route="/b",status="200"} 1
+`
+	if got := prometheus(c); got != want {
+		t.Errorf("output =\n%v\nwant\n%v", got, want)
+	}
+	if got := c.Value("/a", "500"); got != 3 {
+		t.Errorf("Value = %v, want 3", got)
+	}
+}
+
+func TestCounterWithoutLabels(t *testing.T) {
+	c := NewCounter("test_logins_total", "Logins.")
+	c.Inc()
+	want := "# HELP test_logins_total Logins.\n# TYPE test_logins_total counter\ntest_logins_total 1\n"
+	if got := prometheus(c); got != want {
+		t.Errorf("output =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestHistogramPrometheus(t *testing.T) {
+	h := NewHistogram("test_duration_seconds", "Durations.", []float64{0.5, 1}, "route")
+	// An observation equal to an upper bound is counted in that bucket.
+	for _, value := range []float64{0.25, 0.5, 0.5, 2} {
+		h.Observe(value, "/a")
+	}
+
+	want := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/a",le="0.5"} 3
+test_duration_seconds_bucket{route="/a",le="1"} 3
+test_duration_seconds_bucket{route="/a",le="+Inf"} 4
+test_duration_seconds_sum{route="/a"} 3.25
+test_duration_seconds_count{route="/a"} 4
+`
+	if got := prometheus(h); got != want {
+		t.Errorf("output =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		names      []string
+		values     []string
+		extraName  string
+		extraValue string
+		want       string
+	}{
+		{name: "no labels", want: ""},
+		{name: "one label", names: []string{"route"}, values: []string{"/a"}, want: `{route="/a"}`},
+		{name: "quote", names: []string{"route"}, values: []string{`say "hi"`}, want: `{route="say \"hi\""}`},
+		{name: "backslash", names: []string{"route"}, values: []string{`a\b`}, want: `{route="a\\b"}`},
+		{name: "newline", names: []string{"route"}, values: []string{"a\nb"}, want: `{route="a\nb"}`},
+		{name: "extra label only", extraName: "le", extraValue: "+Inf", want: `{le="+Inf"}`},
+		{name: "extra label last", names: []string{"route"}, values: []string{"/a"}, extraName: "le", extraValue: "0.5",
+			want: `{route="/a",le="0.5"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLabels(tt.names, tt.values, tt.extraName, tt.extraValue); got != tt.want {
+				t.Errorf("formatLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{value: 0, want: "0"},
+		{value: 0.005, want: "0.005"},
+		{value: 2.5, want: "2.5"},
+		{value: 1e21, want: "1e+21"},
+		{value: math.Inf(1), want: "+Inf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatValue(tt.value); got != tt.want {
+				t.Errorf("formatValue(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	NewCounter("test_twice_total", "Registered twice.")
+	defer func() {
+		if recover() == nil {
+			t.Error("second NewCounter did not panic")
+		}
+	}()
+	NewCounter("test_twice_total", "Registered twice.")
+}
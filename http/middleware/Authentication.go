@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+const PRINCIPAL_KEY = "principal"
+
+// Authenticate rejects requests without a valid JWT or api key
+// and stores the caller in the context under PRINCIPAL_KEY.
+func Authenticate(auth *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := auth.Authenticate(c.Request.Context(), c.GetHeader("Authorization"))
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "Unable to authenticate", "error", err)
+			if apperror.KindOf(err) == apperror.UNAUTHORIZED {
+				c.Header("WWW-Authenticate", `Bearer, ApiKey`)
+			}
+			abortWithError(c, err)
+			return
+		}
+		c.Set(PRINCIPAL_KEY, principal)
+		c.Next()
+	}
+}
+
+// RequireScope rejects api keys that were not granted the scope.
+// Must be used after Authenticate.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).HasScope(scope) {
+			abortWithError(c, apperror.Forbidden("api key was not granted the scope %v", scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAuthMethod rejects callers that authenticated any other way,
+// e.g. api keys must not be able to manage api keys.
+func RequireAuthMethod(method string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetPrincipal(c).AuthMethod != method {
+			abortWithError(c, apperror.Forbidden("only available with %v authentication", method))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAuthority rejects users that were not granted the authority.
+// Must be used after Authenticate.
+func RequireAuthority(authority string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).HasAuthority(authority) {
+			abortWithError(c, apperror.Forbidden("user was not granted the authority %v", authority))
+			return
+		}
+		c.Next()
+	}
+}
+
+func GetPrincipal(c *gin.Context) *entities.Principal {
+	return c.MustGet(PRINCIPAL_KEY).(*entities.Principal)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/config"
+)
+
+var (
+	DEFAULT_CORS_METHODS = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	// Request headers a browser may send besides the CORS-safelisted ones.
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", REQUEST_ID_HEADER, "traceparent", "tracestate"}
+	// Response headers a browser lets the frontend read besides the CORS-safelisted ones.
+	corsExposedHeaders = []string{REQUEST_ID_HEADER, RATE_LIMIT_LIMIT_HEADER, RATE_LIMIT_REMAINING_HEADER,
+		RATE_LIMIT_RESET_HEADER, RETRY_AFTER_HEADER, "WWW-Authenticate"}
+)
+
+// CORS lets the allowed origins call the api from a browser. Preflight requests are answered here,
+// a preflight of any other origin is rejected with 403. Other requests of an unknown origin
+// are handled without CORS headers, so the browser hides the response from the calling page.
+func CORS(corsConfig config.CORSConfig) gin.HandlerFunc {
+	origins := make(map[string]bool, len(corsConfig.AllowedOrigins))
+	for _, origin := range corsConfig.AllowedOrigins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	methods := corsConfig.AllowedMethods
+	if len(methods) == 0 {
+		methods = DEFAULT_CORS_METHODS
+	}
+	methods = slices.Clone(methods)
+	for i := range methods {
+		methods[i] = strings.ToUpper(methods[i])
+	}
+	allowedMethods := strings.Join(methods, ", ")
+	allowedHeaders := strings.Join(corsAllowedHeaders, ", ")
+	exposedHeaders := strings.Join(corsExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(corsConfig.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if len(origins) == 0 || origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		// Responses differ by origin, caches must not serve them to another one.
+		c.Writer.Header().Add("Vary", "Origin")
+		if !origins[origin] && !origins[config.ANY_ORIGIN] {
+			if preflight {
+				abortWithError(c, apperror.Forbidden("origin is not allowed: %v", origin))
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if corsConfig.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", exposedHeaders)
+			c.Next()
+			return
+		}
+		requestedMethod := strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+		if !slices.Contains(methods, requestedMethod) {
+			abortWithError(c, apperror.Forbidden("method is not allowed: %v", requestedMethod))
+			return
+		}
+		c.Header("Access-Control-Allow-Methods", allowedMethods)
+		c.Header("Access-Control-Allow-Headers", allowedHeaders)
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
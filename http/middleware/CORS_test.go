@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/config"
+)
+
+func newCORSRouter(corsConfig config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorResponse(), CORS(corsConfig))
+	router.GET("/BlogEntry", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/BlogEntry", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return router
+}
+
+func TestCORS(t *testing.T) {
+	allowed := config.CORSConfig{
+		AllowedOrigins:   []string{"https://blog.example.com/"},
+		AllowedMethods:   []string{"get", "POST"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	tests := []struct {
+		name   string
+		config config.CORSConfig
+		method string
+		// Origin and Access-Control-Request-Method of the request, the latter is not sent if empty.
+		origin          string
+		preflightMethod string
+		wantStatus      int
+		wantHeaders     map[string]string
+	}{
+		{
+			name:            "preflight of an allowed origin",
+			config:          allowed,
+			method:          http.MethodOptions,
+			origin:          "https://blog.example.com",
+			preflightMethod: "post",
+			wantStatus:      http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://blog.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type, X-Request-ID, traceparent, tracestate",
+				"Access-Control-Max-Age":           "600",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:            "preflight of a method that is not allowed",
+			config:          allowed,
+			method:          http.MethodOptions,
+			origin:          "https://blog.example.com",
+			preflightMethod: http.MethodDelete,
+			wantStatus:      http.StatusForbidden,
+			wantHeaders:     map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:            "preflight of an unknown origin",
+			config:          allowed,
+			method:          http.MethodOptions,
+			origin:          "https://evil.example.com",
+			preflightMethod: http.MethodGet,
+			wantStatus:      http.StatusForbidden,
+			wantHeaders:     map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name:            "preflight of any origin",
+			config:          config.CORSConfig{AllowedOrigins: []string{config.ANY_ORIGIN}},
+			method:          http.MethodOptions,
+			origin:          "https://evil.example.com",
+			preflightMethod: http.MethodPut,
+			wantStatus:      http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://evil.example.com",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, DELETE",
+				"Access-Control-Max-Age":           "0",
+			},
+		},
+		{
+			name:       "request of an allowed origin",
+			config:     allowed,
+			method:     http.MethodGet,
+			origin:     "https://blog.example.com",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://blog.example.com",
+				"Access-Control-Expose-Headers": "X-Request-ID, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, WWW-Authenticate",
+				"Access-Control-Allow-Methods":  "",
+			},
+		},
+		{
+			name:        "request of an unknown origin is served without CORS headers",
+			config:      allowed,
+			method:      http.MethodPost,
+			origin:      "https://evil.example.com",
+			wantStatus:  http.StatusCreated,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name:        "OPTIONS without a preflight method is no preflight",
+			config:      allowed,
+			method:      http.MethodOptions,
+			origin:      "https://blog.example.com",
+			wantStatus:  http.StatusNotFound,
+			wantHeaders: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:            "disabled",
+			config:          config.CORSConfig{},
+			method:          http.MethodOptions,
+			origin:          "https://blog.example.com",
+			preflightMethod: http.MethodGet,
+			wantStatus:      http.StatusNotFound,
+			wantHeaders:     map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "/BlogEntry", nil)
+			request.Header.Set("Origin", tt.origin)
+			if tt.preflightMethod != "" {
+				request.Header.Set("Access-Control-Request-Method", tt.preflightMethod)
+			}
+			recorder := httptest.NewRecorder()
+			newCORSRouter(tt.config).ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", recorder.Code, tt.wantStatus)
+			}
+			for header, want := range tt.wantHeaders {
+				if got := recorder.Header().Get(header); got != want {
+					t.Errorf("%v = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+var kindStatus = map[apperror.Kind]int{
+	apperror.NOT_FOUND:         http.StatusNotFound,
+	apperror.CONFLICT:          http.StatusConflict,
+	apperror.VALIDATION:        http.StatusBadRequest,
+	apperror.UNAUTHORIZED:      http.StatusUnauthorized,
+	apperror.FORBIDDEN:         http.StatusForbidden,
+	apperror.TOO_MANY_REQUESTS: http.StatusTooManyRequests,
+	apperror.TOO_LARGE:         http.StatusRequestEntityTooLarge,
+}
+
+// ErrorResponse turns the last error a handler recorded with c.Error into an
+// application/problem+json response. Errors of the apperror package get the status of their kind
+// and their message as detail, any other error is logged and reported without details:
+// 499 if the client went away, 504 if the request ran out of time and 500 otherwise.
+func ErrorResponse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last().Err
+		if c.Writer.Written() {
+			slog.ErrorContext(c.Request.Context(), "Unable to run handler after responding", "error", err)
+			return
+		}
+
+		problem := dto.Problem{Type: dto.PROBLEM_TYPE_BLANK, Instance: c.Request.URL.Path}
+		var appErr *apperror.Error
+		if errors.As(err, &appErr) {
+			problem.Status = kindStatus[appErr.Kind]
+			problem.Detail = appErr.Message
+			problem.Errors = appErr.Fields
+		} else {
+			slog.ErrorContext(c.Request.Context(), "Unable to run handler", "error", err)
+			switch problem.Status = ContextErrorStatus(c.Request.Context()); problem.Status {
+			case STATUS_CLIENT_CLOSED_REQUEST:
+				problem.Detail = "The client closed the request"
+			case http.StatusGatewayTimeout:
+				problem.Detail = "The request timed out"
+			default:
+				problem.Status = http.StatusInternalServerError
+				problem.Detail = "Failed to process the request"
+			}
+		}
+		if problem.Status == 0 {
+			problem.Status = http.StatusInternalServerError
+		}
+		problem.Title = statusTitle(problem.Status)
+		c.Header("Content-Type", dto.PROBLEM_CONTENT_TYPE)
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// abortWithError records err for ErrorResponse and stops the remaining handlers.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+func statusTitle(status int) string {
+	if status == STATUS_CLIENT_CLOSED_REQUEST {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+func TestErrorResponse(t *testing.T) {
+	canceled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+	expired := func() context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		t.Cleanup(cancel)
+		return ctx
+	}
+	fields := []apperror.FieldError{{Field: "title", Message: "is required"}}
+	tests := []struct {
+		name string
+		err  error
+		// Context of the request, context.Background if nil.
+		requestContext func() context.Context
+		wantStatus     int
+		wantTitle      string
+		wantDetail     string
+		wantFields     []apperror.FieldError
+	}{
+		{name: "not found", err: apperror.NotFound("no blog entry 5"),
+			wantStatus: http.StatusNotFound, wantTitle: "Not Found", wantDetail: "no blog entry 5"},
+		{name: "conflict", err: apperror.Conflict("username is already taken: alice"),
+			wantStatus: http.StatusConflict, wantTitle: "Conflict", wantDetail: "username is already taken: alice"},
+		{name: "validation", err: apperror.Validation("invalid ID: x"),
+			wantStatus: http.StatusBadRequest, wantTitle: "Bad Request", wantDetail: "invalid ID: x"},
+		{name: "validation with fields", err: apperror.InvalidFields(fields...),
+			wantStatus: http.StatusBadRequest, wantTitle: "Bad Request", wantDetail: "request has invalid fields", wantFields: fields},
+		{name: "unauthorized", err: apperror.Unauthorized("invalid token"),
+			wantStatus: http.StatusUnauthorized, wantTitle: "Unauthorized", wantDetail: "invalid token"},
+		{name: "forbidden", err: apperror.Forbidden("missing scope"),
+			wantStatus: http.StatusForbidden, wantTitle: "Forbidden", wantDetail: "missing scope"},
+		{name: "too many requests", err: apperror.TooManyRequests("rate limit exceeded"),
+			wantStatus: http.StatusTooManyRequests, wantTitle: "Too Many Requests", wantDetail: "rate limit exceeded"},
+		{name: "too large", err: apperror.TooLarge("request body is too large"),
+			wantStatus: http.StatusRequestEntityTooLarge, wantTitle: "Request Entity Too Large", wantDetail: "request body is too large"},
+		{name: "wrapped error keeps its kind", err: fmt.Errorf("could not login: %w", apperror.Unauthorized("invalid username or password")),
+			wantStatus: http.StatusUnauthorized, wantTitle: "Unauthorized", wantDetail: "invalid username or password"},
+		{name: "cause is not revealed", err: apperror.Conflict("username is already taken").Wrap(errors.New("duplicate key in users_pkey")),
+			wantStatus: http.StatusConflict, wantTitle: "Conflict", wantDetail: "username is already taken"},
+		{name: "unknown error", err: errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError, wantTitle: "Internal Server Error", wantDetail: "Failed to process the request"},
+		{name: "unknown kind", err: &apperror.Error{Kind: 99, Message: "strange"},
+			wantStatus: http.StatusInternalServerError, wantTitle: "Internal Server Error", wantDetail: "strange"},
+		{name: "client went away", err: context.Canceled, requestContext: canceled,
+			wantStatus: STATUS_CLIENT_CLOSED_REQUEST, wantTitle: "Client Closed Request", wantDetail: "The client closed the request"},
+		{name: "request timed out", err: context.DeadlineExceeded, requestContext: expired,
+			wantStatus: http.StatusGatewayTimeout, wantTitle: "Gateway Timeout", wantDetail: "The request timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(ErrorResponse())
+			router.GET("/BlogEntry/:id", func(c *gin.Context) { c.Error(tt.err) })
+
+			req := httptest.NewRequest(http.MethodGet, "/BlogEntry/5", nil)
+			if tt.requestContext != nil {
+				req = req.WithContext(tt.requestContext())
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != dto.PROBLEM_CONTENT_TYPE {
+				t.Errorf("Content-Type = %q, want %q", got, dto.PROBLEM_CONTENT_TYPE)
+			}
+			var problem dto.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("body is not a problem: %v: %s", err, rec.Body)
+			}
+			want := dto.Problem{Type: dto.PROBLEM_TYPE_BLANK, Title: tt.wantTitle, Status: tt.wantStatus,
+				Detail: tt.wantDetail, Instance: "/BlogEntry/5"}
+			if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status ||
+				problem.Detail != want.Detail || problem.Instance != want.Instance {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+			if !slices.Equal(problem.Errors, tt.wantFields) {
+				t.Errorf("errors = %v, want %v", problem.Errors, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestErrorResponseKeepsWrittenResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorResponse())
+	router.GET("/BlogEntry", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		c.Error(errors.New("failed after responding"))
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/BlogEntry", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("response = %v %q, want the one of the handler", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/logging"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// Request IDs of clients are only propagated if they can't break the logs or the response headers.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID propagates the X-Request-ID of the client, or assigns one, and returns it in the response.
+// The request context carries it together with the Lambda and API Gateway request IDs,
+// so every record logged with the context can be correlated.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var ids logging.RequestIDs
+		if lambdaContext, ok := lambdacontext.FromContext(ctx); ok {
+			ids.LambdaRequestID = lambdaContext.AwsRequestID
+		}
+		if gatewayContext, ok := core.GetAPIGatewayContextFromContext(ctx); ok {
+			ids.GatewayRequestID = gatewayContext.RequestID
+		} else if gatewayContext, ok := core.GetAPIGatewayV2ContextFromContext(ctx); ok {
+			ids.GatewayRequestID = gatewayContext.RequestID
+		}
+		ids.RequestID = c.GetHeader(REQUEST_ID_HEADER)
+		if !requestIDPattern.MatchString(ids.RequestID) {
+			ids.RequestID = ids.GatewayRequestID
+		}
+		if ids.RequestID == "" {
+			ids.RequestID = uuid.NewString()
+		}
+		c.Request = c.Request.WithContext(logging.WithRequestIDs(ctx, ids))
+		c.Header(REQUEST_ID_HEADER, ids.RequestID)
+		c.Next()
+	}
+}
+
+// AccessLog logs every request with its status and latency, after it was handled.
+// The query string is left out, it may contain codes and tokens.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", ClientIP(c)),
+		}
+		if principal, ok := c.Get(PRINCIPAL_KEY); ok {
+			attrs = append(attrs, slog.String("user", principal.(*entities.Principal).Username))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request handled", attrs...)
+	}
+}
+
+// Recovery turns a panic of a handler into an internal error for ErrorResponse and logs its stack.
+// Must be used after ErrorResponse.
+func Recovery() gin.HandlerFunc {
+	// The stack is logged here, not by gin.
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "Recovered from panic",
+			"panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		abortWithError(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/metrics"
+)
+
+// Route of requests that matched no route, so unknown paths don't create new series.
+const UNMATCHED_ROUTE = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounter("http_requests_total",
+		"Requests handled, by method, route and status.", "method", "route", "status")
+	httpRequestDuration = metrics.NewHistogram("http_request_duration_seconds",
+		"Time taken to handle requests, by method and route.", metrics.DEFAULT_BUCKETS, "method", "route")
+)
+
+// Metrics counts every request and records its latency by its route, not its path.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = UNMATCHED_ROUTE
+		}
+		httpRequestDuration.ObserveSince(start, c.Request.Method, route)
+		httpRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
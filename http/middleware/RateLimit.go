@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/metrics"
+	"github.com/skyrenx/blog-api-go/http/ratelimit"
+)
+
+// Headers of the IETF draft "RateLimit header fields for HTTP".
+const (
+	RATE_LIMIT_LIMIT_HEADER     = "RateLimit-Limit"
+	RATE_LIMIT_REMAINING_HEADER = "RateLimit-Remaining"
+	RATE_LIMIT_RESET_HEADER     = "RateLimit-Reset"
+	RETRY_AFTER_HEADER          = "Retry-After"
+
+	// Holds the result whose headers were set, so the strictest of several policies is reported.
+	rateLimitResultKey = "rateLimitResult"
+)
+
+var rateLimited = metrics.NewCounter("http_rate_limited_total",
+	"Requests rejected because a rate limit was exceeded, by policy.", "policy")
+
+// KeyFunc returns the key of the bucket a request takes its token from.
+type KeyFunc func(c *gin.Context) string
+
+// ClientIPKey keys requests by the IP of the client.
+func ClientIPKey(c *gin.Context) string {
+	return "ip:" + ClientIP(c)
+}
+
+// ClientIP returns the IP of the client. In Lambda, gin cannot tell it from the converted request,
+// so it is taken from the event: API Gateway and Function URLs report the source IP in the request context,
+// an ALB appends it to X-Forwarded-For, where the entries before it were sent by the client.
+// Outside of Lambda it is the address of the peer, no proxy is trusted.
+func ClientIP(c *gin.Context) string {
+	ctx := c.Request.Context()
+	if gatewayContext, ok := core.GetAPIGatewayContextFromContext(ctx); ok {
+		return gatewayContext.Identity.SourceIP
+	}
+	if gatewayContext, ok := core.GetAPIGatewayV2ContextFromContext(ctx); ok {
+		return gatewayContext.HTTP.SourceIP
+	}
+	if _, ok := core.GetTargetGroupRequetFromContextALB(ctx); ok {
+		forwardedFor := c.Request.Header.Values("X-Forwarded-For")
+		if len(forwardedFor) > 0 {
+			entries := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(entries[len(entries)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	return c.ClientIP()
+}
+
+// PrincipalKey keys requests by the api key or the user they authenticated with, so every api key
+// of a user has its own limit. Requests that did not authenticate are keyed by ClientIPKey.
+func PrincipalKey(c *gin.Context) string {
+	value, ok := c.Get(PRINCIPAL_KEY)
+	if !ok {
+		return ClientIPKey(c)
+	}
+	principal := value.(*entities.Principal)
+	if principal.AuthMethod == entities.AUTH_METHOD_API_KEY {
+		return "apikey:" + principal.ApiKeyID
+	}
+	return "user:" + principal.Username
+}
+
+// RateLimitPolicy limits the requests that share a key.
+type RateLimitPolicy struct {
+	Name  string // Keeps the buckets of policies apart and labels the metric of rejected requests
+	Limit ratelimit.Limit
+	Key   KeyFunc
+}
+
+// RateLimit rejects requests with 429 Too Many Requests once the bucket of their key is empty,
+// and reports the state of the bucket in the RateLimit-* headers. A disabled limit lets all requests pass.
+// If the store fails, requests pass as well, so the api keeps working without it.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !policy.Limit.Enabled() || takeToken(c, store, policy.Name, policy.Name+":"+policy.Key(c), policy.Limit) {
+			c.Next()
+		}
+	}
+}
+
+// RouteRateLimit applies the limits of single routes, keyed by method and route like "POST /User/register".
+// Every route has its own buckets, which are keyed by key. Requests to other routes pass.
+func RouteRateLimit(store ratelimit.Store, routes map[string]ratelimit.Limit, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := routes[route]
+		if !ok || !limit.Enabled() || takeToken(c, store, "route", "route:"+route+":"+key(c), limit) {
+			c.Next()
+		}
+	}
+}
+
+// takeToken takes a token of the bucket of key and aborts the request if there was none.
+func takeToken(c *gin.Context, store ratelimit.Store, policy string, key string, limit ratelimit.Limit) bool {
+	result, err := store.Take(c.Request.Context(), key, limit)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "Unable to check rate limit", "policy", policy, "error", err)
+		return true
+	}
+	setRateLimitHeaders(c, limit, result)
+	if result.Allowed {
+		return true
+	}
+	rateLimited.Inc(policy)
+	c.Header(RETRY_AFTER_HEADER, formatSeconds(result.RetryAfter))
+	abortWithError(c, apperror.TooManyRequests("rate limit exceeded, retry in %v seconds", formatSeconds(result.RetryAfter)))
+	return false
+}
+
+// setRateLimitHeaders reports result unless another policy already reported fewer remaining requests.
+func setRateLimitHeaders(c *gin.Context, limit ratelimit.Limit, result ratelimit.Result) {
+	if previous, ok := c.Get(rateLimitResultKey); ok && previous.(ratelimit.Result).Remaining <= result.Remaining && result.Allowed {
+		return
+	}
+	c.Set(rateLimitResultKey, result)
+	c.Header(RATE_LIMIT_LIMIT_HEADER, strconv.Itoa(limit.Burst))
+	c.Header(RATE_LIMIT_REMAINING_HEADER, strconv.Itoa(result.Remaining))
+	c.Header(RATE_LIMIT_RESET_HEADER, formatSeconds(result.Reset))
+}
+
+// formatSeconds rounds up to whole seconds, so a client that waits that long finds a token.
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+)
+
+const (
+	// Browsers only use HTTPS for the api for a year after they saw it there.
+	HSTS_MAX_AGE = 365 * 24 * 60 * 60
+	// The api renders no pages. Pages of gin or net/http, e.g. the body of a redirect,
+	// must not load anything or be framed. Browsers ignore the policy for JSON.
+	CONTENT_SECURITY_POLICY = "default-src 'none'; frame-ancestors 'none'"
+
+	// Limit of BodyLimit if it is given none.
+	DEFAULT_MAX_BODY_SIZE = 1 << 20
+)
+
+// SecurityHeaders adds the headers that keep browsers from sniffing, framing or downgrading responses.
+// HSTS is only sent over HTTPS, directly or behind API Gateway or a load balancer that set X-Forwarded-Proto.
+func SecurityHeaders() gin.HandlerFunc {
+	hsts := "max-age=" + strconv.Itoa(HSTS_MAX_AGE)
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Content-Security-Policy", CONTENT_SECURITY_POLICY)
+		header.Set("Referrer-Policy", "no-referrer")
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// BodyLimit rejects request bodies larger than maxBytes with 413 Content Too Large.
+// Bodies of a known length are rejected before they are read, any other body stops
+// being read at the limit, which makes binding it fail with the same error.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		maxBytes = DEFAULT_MAX_BODY_SIZE
+	}
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			abortWithError(c, apperror.TooLarge("request body exceeds %v bytes", maxBytes))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Non-standard status for requests the client gave up on, as used by nginx.
+	STATUS_CLIENT_CLOSED_REQUEST = 499
+	// Time kept before the end of the Lambda invocation to send the response.
+	DEADLINE_MARGIN = 250 * time.Millisecond
+	// Default request timeout, which applies when the invocation has no deadline, e.g. with sam local.
+	DEFAULT_REQUEST_TIMEOUT = 30 * time.Second
+)
+
+// RequestTimeout ends the request context shortly before the Lambda invocation times out,
+// so that queries are cancelled and a 504 can still be sent.
+// Without an invocation deadline timeout applies, DEFAULT_REQUEST_TIMEOUT if it is 0.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	if timeout <= 0 {
+		timeout = DEFAULT_REQUEST_TIMEOUT
+	}
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var cancel context.CancelFunc
+		if deadline, ok := ctx.Deadline(); ok {
+			ctx, cancel = context.WithDeadline(ctx, deadline.Add(-DEADLINE_MARGIN))
+		} else {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ContextErrorStatus returns 499 if the client went away and 504 if the request ran out of time,
+// or 0 while the request context is still active.
+func ContextErrorStatus(ctx context.Context) int {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.Canceled):
+		return STATUS_CLIENT_CLOSED_REQUEST
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,144 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skyrenx/blog-api-go/http/oidc"
+	"github.com/skyrenx/blog-api-go/http/oidc/oidctest"
+)
+
+const (
+	CLIENT_ID    = "blog-api"
+	SUBJECT      = "user-1"
+	REDIRECT_URL = "http://localhost:3000/User/oidc/callback"
+)
+
+// authorize follows the authorization url to the mock provider and returns the code and state of the callback.
+func authorize(t *testing.T, authCodeURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authCodeURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned %v", resp.StatusCode)
+	}
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid callback: %v", err)
+	}
+	if !strings.HasPrefix(callback.String(), REDIRECT_URL+"?") {
+		t.Fatalf("callback %v does not go to %v", callback, REDIRECT_URL)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides jwt.MapClaims
+		// Verifier sent to the token endpoint instead of the one of the flow.
+		codeVerifier string
+		// Nonce expected by VerifyIDToken instead of the one of the flow.
+		nonce         string
+		wantExchange  string
+		wantVerifyErr string
+	}{
+		{name: "valid"},
+		{name: "wrong code verifier", codeVerifier: "not-the-verifier", wantExchange: "invalid_grant"},
+		{name: "nonce mismatch", nonce: "another-nonce", wantVerifyErr: "nonce mismatch"},
+		{name: "wrong audience", overrides: jwt.MapClaims{"aud": "another-client"}, wantVerifyErr: "audience"},
+		{name: "expired", overrides: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, wantVerifyErr: "expired"},
+		{name: "wrong issuer", overrides: jwt.MapClaims{"iss": "https://attacker.example.com"}, wantVerifyErr: "issuer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := oidctest.NewServer(CLIENT_ID, SUBJECT, jwt.MapClaims{"preferred_username": "alice"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+			server.Overrides = tt.overrides
+
+			ctx := context.Background()
+			provider, err := oidc.NewProvider(ctx, oidc.Config{
+				Issuer:      server.URL,
+				ClientID:    CLIENT_ID,
+				RedirectURL: REDIRECT_URL,
+				Scopes:      []string{"openid", "profile"},
+			})
+			if err != nil {
+				t.Fatalf("discovery failed: %v", err)
+			}
+			state, _ := oidc.RandomToken(32)
+			nonce, _ := oidc.RandomToken(32)
+			codeVerifier, _ := oidc.NewCodeVerifier()
+
+			code, returnedState := authorize(t, provider.AuthCodeURL(state, nonce, codeVerifier))
+			if returnedState != state {
+				t.Fatalf("state = %q, want %q", returnedState, state)
+			}
+			if tt.codeVerifier != "" {
+				codeVerifier = tt.codeVerifier
+			}
+			token, err := provider.Exchange(ctx, code, codeVerifier)
+			if tt.wantExchange != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantExchange) {
+					t.Fatalf("Exchange error = %v, want %q", err, tt.wantExchange)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange failed: %v", err)
+			}
+
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			claims, err := provider.VerifyIDToken(ctx, token.IDToken, nonce)
+			if tt.wantVerifyErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantVerifyErr) {
+					t.Fatalf("VerifyIDToken error = %v, want %q", err, tt.wantVerifyErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken failed: %v", err)
+			}
+			if claims.Subject != SUBJECT || claims.PreferredUsername != "alice" {
+				t.Errorf("claims = %+v, want subject %v and username alice", claims, SUBJECT)
+			}
+		})
+	}
+}
+
+func TestCodeIsRedeemedOnce(t *testing.T) {
+	server, err := oidctest.NewServer(CLIENT_ID, SUBJECT, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ctx := context.Background()
+	provider, err := oidc.NewProvider(ctx, oidc.Config{Issuer: server.URL, ClientID: CLIENT_ID, RedirectURL: REDIRECT_URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codeVerifier, _ := oidc.NewCodeVerifier()
+	code, _ := authorize(t, provider.AuthCodeURL("state", "nonce", codeVerifier))
+	if _, err := provider.Exchange(ctx, code, codeVerifier); err != nil {
+		t.Fatalf("first Exchange failed: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, code, codeVerifier); err == nil {
+		t.Fatal("second Exchange of the same code succeeded")
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Interval in which MemoryStore forgets the buckets that are full again.
+const SWEEP_INTERVAL = time.Minute
+
+// MemoryStore keeps the buckets in the memory of the process.
+// A full bucket behaves like a missing one, so idle keys are removed periodically.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	sweptAt time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), sweptAt: time.Now()}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{}, ErrInvalidLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.sweptAt) >= SWEEP_INTERVAL {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Burst), updatedAt: now}}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	return b.take(now, limit), nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.full(now, b.limit) {
+			delete(s.buckets, key)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,76 @@
+// Package ratelimit limits how often a client may call the api with token buckets.
+//
+// Every key, e.g. a client IP or a user, has a bucket that holds up to Burst tokens
+// and is refilled with Rate tokens per second. A request takes a token and is rejected
+// if the bucket is empty. The buckets live in a Store: MemoryStore keeps them in the process,
+// which limits every instance on its own. Deployments with several instances or Lambda
+// execution environments can share the buckets with their own implementation of Store.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// Limit is the policy of a bucket.
+type Limit struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Size of the bucket, requests that may be sent at once
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the state of a bucket after a token was taken.
+type Result struct {
+	Allowed   bool
+	Remaining int           // Tokens left in the bucket
+	Reset     time.Duration // Time until the bucket is full again
+	// Time until the next token is available, 0 if the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take must update a bucket atomically,
+// so concurrent requests of a key never take the same token.
+type Store interface {
+	// Take takes a token from the bucket of key, which is created full if it does not exist.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ErrInvalidLimit is returned by Take for a limit that is not Enabled.
+var ErrInvalidLimit = errors.New("rate limit must have a positive rate and burst")
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills the bucket for the time passed since its last update and takes a token if there is one.
+func (b *bucket) take(now time.Time, limit Limit) Result {
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate)
+	b.updatedAt = now
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = secondsDuration((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return result
+}
+
+// full reports whether the bucket has been refilled completely by now, so it can be forgotten.
+func (b *bucket) full(now time.Time, limit Limit) bool {
+	return b.tokens+now.Sub(b.updatedAt).Seconds()*limit.Rate >= float64(limit.Burst)
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
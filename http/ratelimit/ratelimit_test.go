@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	start := time.Unix(0, 0)
+	type take struct {
+		after          time.Duration // Since start
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantReset      time.Duration
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst",
+			takes: []take{
+				{wantAllowed: true, wantRemaining: 2, wantReset: 500 * time.Millisecond},
+				{wantAllowed: true, wantRemaining: 1, wantReset: time.Second},
+				{wantAllowed: true, wantRemaining: 0, wantReset: 1500 * time.Millisecond},
+				{wantRetryAfter: 500 * time.Millisecond, wantReset: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill at the rate",
+			takes: []take{
+				{wantAllowed: true, wantRemaining: 2, wantReset: 500 * time.Millisecond},
+				{wantAllowed: true, wantRemaining: 1, wantReset: time.Second},
+				{wantAllowed: true, wantRemaining: 0, wantReset: 1500 * time.Millisecond},
+				{after: 250 * time.Millisecond, wantRetryAfter: 250 * time.Millisecond, wantReset: 1250 * time.Millisecond},
+				{after: 500 * time.Millisecond, wantAllowed: true, wantRemaining: 0, wantReset: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill stops at the burst",
+			takes: []take{
+				{wantAllowed: true, wantRemaining: 2, wantReset: 500 * time.Millisecond},
+				{after: time.Hour, wantAllowed: true, wantRemaining: 2, wantReset: 500 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bucket{tokens: float64(limit.Burst), updatedAt: start}
+			for i, take := range tt.takes {
+				result := b.take(start.Add(take.after), limit)
+				want := Result{Allowed: take.wantAllowed, Remaining: take.wantRemaining, Reset: take.wantReset, RetryAfter: take.wantRetryAfter}
+				if result != want {
+					t.Fatalf("take %v = %+v, want %+v", i+1, result, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketFull(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 2}
+	start := time.Unix(0, 0)
+	tests := []struct {
+		tokens float64
+		after  time.Duration
+		want   bool
+	}{
+		{tokens: 2, want: true},
+		{tokens: 1, want: false},
+		{tokens: 1, after: time.Second, want: true},
+		{tokens: 0, after: 1999 * time.Millisecond, want: false},
+	}
+	for _, tt := range tests {
+		b := &bucket{tokens: tt.tokens, updatedAt: start}
+		if got := b.full(start.Add(tt.after), limit); got != tt.want {
+			t.Errorf("bucket with %v tokens after %v: full = %v, want %v", tt.tokens, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       Limit
+		keys        []string
+		wantAllowed []bool
+		wantErr     error
+	}{
+		{name: "keys have their own buckets", limit: Limit{Rate: 0.001, Burst: 1}, keys: []string{"a", "b", "a", "b"},
+			wantAllowed: []bool{true, true, false, false}},
+		{name: "burst of a key", limit: Limit{Rate: 0.001, Burst: 2}, keys: []string{"a", "a", "a"},
+			wantAllowed: []bool{true, true, false}},
+		{name: "zero rate", limit: Limit{Rate: 0, Burst: 1}, keys: []string{"a"}, wantErr: ErrInvalidLimit},
+		{name: "zero burst", limit: Limit{Rate: 1, Burst: 0}, keys: []string{"a"}, wantErr: ErrInvalidLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, key := range tt.keys {
+				result, err := store.Take(context.Background(), key, tt.limit)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Allowed != tt.wantAllowed[i] {
+					t.Fatalf("take %v of %v: allowed = %v, want %v", i+1, key, result.Allowed, tt.wantAllowed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	store.Take(context.Background(), "idle", limit)
+	store.Take(context.Background(), "busy", Limit{Rate: 0.001, Burst: 1})
+
+	// Both buckets were emptied a minute ago, only the one with the higher rate is full again.
+	for _, b := range store.buckets {
+		b.updatedAt = b.updatedAt.Add(-SWEEP_INTERVAL)
+	}
+	store.sweep(time.Now())
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("full bucket was kept")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Error("bucket that is not full was removed")
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Default of Config.QueryTimeout.
+const DEFAULT_QUERY_TIMEOUT = 5 * time.Second
+
+// queryContext bounds a repository operation by timeout, DEFAULT_QUERY_TIMEOUT if it is 0.
+// An earlier deadline of ctx, like the end of the Lambda invocation, still applies.
+// The operation is traced as a span named by query until cancel is called, which also
+// records its duration as the latency of query.
+func queryContext(ctx context.Context, timeout time.Duration, query string) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DEFAULT_QUERY_TIMEOUT
+	}
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "repository."+query)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		span.End()
+		queryDuration.ObserveSince(start, query)
+	}
+}
+
+func (p *Postgres) queryContext(ctx context.Context, query string) (context.Context, context.CancelFunc) {
+	return queryContext(ctx, p.queryTimeout, query)
+}
+
+func (s *SQLite) queryContext(ctx context.Context, query string) (context.Context, context.CancelFunc) {
+	return queryContext(ctx, s.queryTimeout, query)
+}
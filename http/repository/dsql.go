@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	DEFAULT_REGION        = "us-east-1"
+	TOKEN_EXPIRATION_TIME = 15 //Minutes
+	// A cached token is replaced this long before it expires.
+	TOKEN_REFRESH_MARGIN = 5 * time.Minute
+)
+
+// NewDSQL connects to the Aurora DSQL cluster at clusterEndpoint in region with IAM authentication.
+// https://docs.aws.amazon.com/aurora-dsql/latest/userguide/SECTION_program-with-go.html
+func NewDSQL(clusterEndpoint string, region string) *Postgres {
+	if region == "" {
+		region = DEFAULT_REGION
+	}
+	tokens := &dsqlTokenCache{clusterEndpoint: clusterEndpoint, region: region}
+	return &Postgres{poolConfig: func() (*pgxpool.Config, error) {
+		if clusterEndpoint == "" {
+			return nil, fmt.Errorf("CLUSTER_ENDPOINT is not set")
+		}
+
+		// Build connection URL
+		var sb strings.Builder
+		sb.WriteString("postgres://")
+		sb.WriteString(clusterEndpoint)
+		sb.WriteString(":5432/postgres?user=admin&sslmode=verify-full")
+		config, err := pgxpool.ParseConfig(sb.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+		// Every new connection of the pool authenticates with a valid token.
+		config.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			token, err := tokens.get(ctx)
+			if err != nil {
+				return err
+			}
+			// To avoid issues with parse config set the password directly in config
+			connConfig.Password = token
+			return nil
+		}
+		return config, nil
+	}}
+}
+
+// dsqlTokenCache reuses an auth token until shortly before it expires,
+// instead of presigning a new one for every connection.
+type dsqlTokenCache struct {
+	clusterEndpoint string
+	region          string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *dsqlTokenCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expiresAt.Add(-TOKEN_REFRESH_MARGIN)) {
+		dsqlTokens.Inc(TOKEN_RESULT_CACHED)
+		return c.token, nil
+	}
+
+	start := time.Now()
+	token, err := c.generate(ctx)
+	dsqlTokenDuration.ObserveSince(start)
+	if err != nil {
+		dsqlTokens.Inc(TOKEN_RESULT_ERROR)
+		return "", err
+	}
+	dsqlTokens.Inc(TOKEN_RESULT_GENERATED)
+	c.token = token
+	c.expiresAt = start.Add(TOKEN_EXPIRATION_TIME * time.Minute)
+	return token, nil
+}
+
+func (c *dsqlTokenCache) generate(ctx context.Context) (string, error) {
+	_, span := tracer.Start(ctx, "aws.NewSession")
+	sess, err := session.NewSession()
+	if err != nil {
+		endSpan(span, err)
+		return "", err
+	}
+
+	creds, err := sess.Config.Credentials.Get()
+	endSpan(span, err)
+	if err != nil {
+		return "", err
+	}
+	staticCredentials := credentials.NewStaticCredentials(
+		creds.AccessKeyID,
+		creds.SecretAccessKey,
+		creds.SessionToken,
+	)
+
+	// The token expiration time is optional, and the default value 900 seconds (15 minutes)
+	// If you are not connecting as admin, use DbConnect action instead
+	_, span = tracer.Start(ctx, "dsql.PresignAuthToken")
+	token, err := generateDbConnectAdminAuthToken(staticCredentials, c.clusterEndpoint, c.region)
+	endSpan(span, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate auth token: %w", err)
+	}
+	return token, nil
+}
+
+// generate password token to connect to your Aurora DSQL cluster.
+func generateDbConnectAdminAuthToken(creds *credentials.Credentials, clusterEndpoint string, region string) (string, error) {
+	// the scheme is arbitrary and is only needed because validation of the URL requires one.
+	endpoint := "https://" + clusterEndpoint
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	values := req.URL.Query()
+	values.Set("Action", "DbConnectAdmin")
+	req.URL.RawQuery = values.Encode()
+
+	signer := v4.Signer{
+		Credentials: creds,
+	}
+	_, err = signer.Presign(req, nil, "dsql", region, TOKEN_EXPIRATION_TIME*time.Minute, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	url := req.URL.String()[len("https://"):]
+
+	return url, nil
+}
@@ -0,0 +1,487 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+// Memory implements the repositories in memory, for unit tests and offline development.
+// It mirrors the behavior of Postgres, including the ids handed out by blog_entry_sequence.
+// Everything is lost when the process exits.
+type Memory struct {
+	mu          sync.RWMutex
+	nextId      int64
+	blogEntries map[entities.BlogEntryID]entities.BlogEntry
+	users       map[string]entities.User
+	authorities map[string][]string
+	apiKeys     map[string]entities.ApiKey
+	identities  map[string]entities.UserIdentity
+	sessions    map[string]entities.Session
+	auditLog    []entities.AuditLogEntry
+}
+
+var (
+	_ BlogEntryRepository = (*Memory)(nil)
+	_ UserRepository      = (*Memory)(nil)
+	_ ApiKeyRepository    = (*Memory)(nil)
+	_ AuditLogRepository  = (*Memory)(nil)
+)
+
+func NewMemory() *Memory {
+	return &Memory{
+		// Same as the initial row of blog_entry_sequence.
+		nextId:      1,
+		blogEntries: make(map[entities.BlogEntryID]entities.BlogEntry),
+		users:       make(map[string]entities.User),
+		authorities: make(map[string][]string),
+		apiKeys:     make(map[string]entities.ApiKey),
+		identities:  make(map[string]entities.UserIdentity),
+		sessions:    make(map[string]entities.Session),
+	}
+}
+
+// Ping always succeeds, there is no database.
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *Memory) CountBlogEntries(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.blogEntries), nil
+}
+
+func (m *Memory) GetBlogEntries(ctx context.Context, limit int, offset int) ([]entities.BlogEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return paginate(m.sortedBlogEntries(func(entities.BlogEntry) bool { return true }), limit, offset), nil
+}
+
+func (m *Memory) GetBlogEntrySummaries(ctx context.Context, limit int, offset int) ([]dto.BlogEntrySummary, error) {
+	blogEntries, _ := m.GetBlogEntries(ctx, limit, offset)
+	summaries := make([]dto.BlogEntrySummary, len(blogEntries))
+	for i, blogEntry := range blogEntries {
+		summaries[i] = dto.BlogEntrySummary{
+			ID:        blogEntry.ID,
+			Title:     blogEntry.Title,
+			Author:    blogEntry.Author,
+			CreatedAt: blogEntry.CreatedAt,
+		}
+	}
+	return summaries, nil
+}
+
+func (m *Memory) GetBlogEntriesByAuthor(ctx context.Context, author string) ([]entities.BlogEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sortedBlogEntries(func(blogEntry entities.BlogEntry) bool { return blogEntry.Author == author }), nil
+}
+
+func (m *Memory) GetBlogEntryById(ctx context.Context, id entities.BlogEntryID) (*entities.BlogEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	blogEntry, found := m.blogEntries[id]
+	if !found {
+		return nil, apperror.NotFound("no blog entry found with id %v", id)
+	}
+	return &blogEntry, nil
+}
+
+func (m *Memory) CreateBlogEntry(ctx context.Context, entry entities.BlogEntry) (entities.BlogEntryID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := entry.ID
+	if id == "" {
+		id = entities.LegacyBlogEntryID(m.nextId)
+		m.nextId++
+	}
+	if _, found := m.blogEntries[id]; found {
+		return "", apperror.Conflict("blog entry already exists: %v", id)
+	}
+	now := time.Now()
+	entry.ID = id
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	m.blogEntries[id] = entry
+	return id, nil
+}
+
+// sortedBlogEntries returns the matching entries, newest first. Must be called with the lock held.
+func (m *Memory) sortedBlogEntries(match func(entities.BlogEntry) bool) []entities.BlogEntry {
+	blogEntries := []entities.BlogEntry{}
+	for _, blogEntry := range m.blogEntries {
+		if match(blogEntry) {
+			blogEntries = append(blogEntries, blogEntry)
+		}
+	}
+	sort.Slice(blogEntries, func(i, j int) bool {
+		if blogEntries[i].CreatedAt.Equal(blogEntries[j].CreatedAt) {
+			return blogEntries[i].ID > blogEntries[j].ID
+		}
+		return blogEntries[i].CreatedAt.After(blogEntries[j].CreatedAt)
+	})
+	return blogEntries
+}
+
+func (m *Memory) GetUserByUsername(ctx context.Context, username string) (*dto.UserWithoutPassword, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, found := m.users[username]
+	if !found {
+		return nil, apperror.NotFound("no user found with username %v", username)
+	}
+	return &dto.UserWithoutPassword{Username: user.Username, Enabled: user.Enabled}, nil
+}
+
+func (m *Memory) GetUserWithPassword(ctx context.Context, username string) (*entities.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, found := m.users[username]
+	if !found {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (m *Memory) GetUserAccount(ctx context.Context, username string) (*dto.UserAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, found := m.users[username]
+	if !found {
+		return nil, nil
+	}
+	account := m.userAccount(user)
+	return &account, nil
+}
+
+func (m *Memory) SearchUserAccounts(ctx context.Context, search string, pageNumber int, pageSize int) ([]dto.UserAccount, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	search = strings.ToLower(search)
+	accounts := []dto.UserAccount{}
+	for _, user := range m.users {
+		if strings.Contains(strings.ToLower(user.Username), search) {
+			accounts = append(accounts, m.userAccount(user))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Username < accounts[j].Username })
+	return paginate(accounts, pageSize, (pageNumber-1)*pageSize), len(accounts), nil
+}
+
+func (m *Memory) RegisterUser(ctx context.Context, user entities.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, found := m.users[user.Username]; found {
+		return apperror.Conflict("username is already taken: %v", user.Username)
+	}
+	m.users[user.Username] = entities.User{Username: user.Username, Password: user.Password, Enabled: true}
+	return nil
+}
+
+func (m *Memory) UpdatePassword(ctx context.Context, username string, hashedPassword string, changedAt time.Time) (bool, error) {
+	return m.updateUser(username, nil, func(user *entities.User) {
+		user.Password = hashedPassword
+		user.PasswordResetRequired = false
+		user.PasswordChangedAt = &changedAt
+	}), nil
+}
+
+func (m *Memory) RehashPassword(ctx context.Context, username string, hashedPassword string) (bool, error) {
+	return m.updateUser(username, nil, func(user *entities.User) { user.Password = hashedPassword }), nil
+}
+
+func (m *Memory) SetUserEnabled(ctx context.Context, username string, enabled bool, audit entities.AuditLogEntry) (bool, error) {
+	return m.updateUser(username, &audit, func(user *entities.User) { user.Enabled = enabled }), nil
+}
+
+func (m *Memory) RequirePasswordReset(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	return m.updateUser(username, &audit, func(user *entities.User) { user.PasswordResetRequired = true }), nil
+}
+
+func (m *Memory) AddAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Contains(m.authorities[authority.Username], authority.Authority) {
+		m.authorities[authority.Username] = append(m.authorities[authority.Username], authority.Authority)
+	}
+	m.auditLog = append(m.auditLog, audit)
+	return nil
+}
+
+func (m *Memory) RemoveAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.authorities[authority.Username] = slices.DeleteFunc(slices.Clone(m.authorities[authority.Username]),
+		func(a string) bool { return a == authority.Authority })
+	m.auditLog = append(m.auditLog, audit)
+	return nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteUserCredentials(username)
+	if _, found := m.users[username]; !found {
+		return false, nil
+	}
+	delete(m.users, username)
+	m.auditLog = append(m.auditLog, audit)
+	return true, nil
+}
+
+func (m *Memory) DeleteAccount(ctx context.Context, username string, request dto.AccountDeletionRequest, alias string, audit entities.AuditLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Validate everything first, there is no rollback.
+	switch request.Entries {
+	case dto.ENTRIES_POLICY_DELETE, dto.ENTRIES_POLICY_ANONYMIZE:
+	case dto.ENTRIES_POLICY_REASSIGN:
+		if _, found := m.users[request.ReassignTo]; !found {
+			return fmt.Errorf("failed to apply entries policy %v of user %v: %w",
+				request.Entries, username, apperror.Validation("reassign_to names no user: %v", request.ReassignTo))
+		}
+	default:
+		return fmt.Errorf("failed to apply entries policy %v of user %v: unknown entries policy: %v",
+			request.Entries, username, request.Entries)
+	}
+
+	for id, blogEntry := range m.blogEntries {
+		if blogEntry.Author != username {
+			continue
+		}
+		switch request.Entries {
+		case dto.ENTRIES_POLICY_DELETE:
+			delete(m.blogEntries, id)
+		case dto.ENTRIES_POLICY_REASSIGN:
+			blogEntry.Author = request.ReassignTo
+			m.blogEntries[id] = blogEntry
+		case dto.ENTRIES_POLICY_ANONYMIZE:
+			blogEntry.Author = alias
+			m.blogEntries[id] = blogEntry
+		}
+	}
+
+	m.deleteUserCredentials(username)
+	_, found := m.users[username]
+	delete(m.users, username)
+	if found && request.Mode == dto.ACCOUNT_DELETION_MODE_ANONYMIZE {
+		m.users[alias] = entities.User{Username: alias, Password: "!", Enabled: false}
+	}
+	m.auditLog = append(m.auditLog, audit)
+	return nil
+}
+
+func (m *Memory) GetUserIdentity(ctx context.Context, issuer string, subject string) (*entities.UserIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	identity, found := m.identities[identityKey(issuer, subject)]
+	if !found {
+		return nil, nil
+	}
+	return &identity, nil
+}
+
+func (m *Memory) GetUserIdentitiesByUsername(ctx context.Context, username string) ([]entities.UserIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	identities := []entities.UserIdentity{}
+	for _, identity := range m.identities {
+		if identity.Username == username {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].CreatedAt.Before(identities[j].CreatedAt) })
+	return identities, nil
+}
+
+func (m *Memory) CreateSession(ctx context.Context, session entities.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *Memory) GetSessionsByUsername(ctx context.Context, username string) ([]entities.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := []entities.Session{}
+	for _, session := range m.sessions {
+		if session.Username == username {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (m *Memory) CreateUserWithIdentity(ctx context.Context, identity entities.UserIdentity, hashedPassword string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, found := m.users[identity.Username]; found {
+		return apperror.Conflict("username is already taken: %v", identity.Username)
+	}
+	key := identityKey(identity.Issuer, identity.Subject)
+	if _, found := m.identities[key]; found {
+		return apperror.Conflict("identity is already linked to a user")
+	}
+	m.users[identity.Username] = entities.User{Username: identity.Username, Password: hashedPassword, Enabled: true}
+	m.identities[key] = identity
+	return nil
+}
+
+func (m *Memory) LinkUserIdentity(ctx context.Context, identity entities.UserIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, found := m.users[identity.Username]; !found {
+		return apperror.NotFound("no user found with username %v", identity.Username)
+	}
+	key := identityKey(identity.Issuer, identity.Subject)
+	if _, found := m.identities[key]; found {
+		return apperror.Conflict("identity is already linked to a user")
+	}
+	m.identities[key] = identity
+	return nil
+}
+
+func (m *Memory) CreateApiKey(ctx context.Context, apiKey entities.ApiKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.apiKeys {
+		if existing.ID == apiKey.ID || existing.KeyHash == apiKey.KeyHash {
+			return fmt.Errorf("failed to insert api key: duplicate key value violates unique constraint: api_keys")
+		}
+	}
+	m.apiKeys[apiKey.ID] = apiKey
+	return nil
+}
+
+func (m *Memory) GetApiKeysByUsername(ctx context.Context, username string) ([]entities.ApiKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	apiKeys := []entities.ApiKey{}
+	for _, apiKey := range m.apiKeys {
+		if apiKey.Username == username {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	sort.Slice(apiKeys, func(i, j int) bool { return apiKeys[i].CreatedAt.After(apiKeys[j].CreatedAt) })
+	return apiKeys, nil
+}
+
+func (m *Memory) GetApiKeyByHash(ctx context.Context, keyHash string) (*entities.ApiKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, apiKey := range m.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			return &apiKey, nil
+		}
+	}
+	return nil, apperror.NotFound("no api key found with the given hash")
+}
+
+func (m *Memory) DeleteApiKey(ctx context.Context, username string, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	apiKey, found := m.apiKeys[id]
+	if !found || apiKey.Username != username {
+		return false, nil
+	}
+	delete(m.apiKeys, id)
+	return true, nil
+}
+
+func (m *Memory) UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if apiKey, found := m.apiKeys[id]; found {
+		apiKey.LastUsedAt = &lastUsedAt
+		m.apiKeys[id] = apiKey
+	}
+	return nil
+}
+
+func (m *Memory) CreateAuditLogEntry(ctx context.Context, entry entities.AuditLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auditLog = append(m.auditLog, entry)
+	return nil
+}
+
+// AuditLog returns a copy of the recorded entries, oldest first.
+func (m *Memory) AuditLog() []entities.AuditLogEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.auditLog)
+}
+
+// updateUser records audit, if not nil, when the user exists.
+func (m *Memory) updateUser(username string, audit *entities.AuditLogEntry, update func(user *entities.User)) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, found := m.users[username]
+	if !found {
+		return false
+	}
+	update(&user)
+	m.users[username] = user
+	if audit != nil {
+		m.auditLog = append(m.auditLog, *audit)
+	}
+	return true
+}
+
+// Must be called with the lock held.
+func (m *Memory) userAccount(user entities.User) dto.UserAccount {
+	authorities := slices.Clone(m.authorities[user.Username])
+	if authorities == nil {
+		authorities = []string{}
+	}
+	slices.Sort(authorities)
+	return dto.UserAccount{
+		Username:              user.Username,
+		Enabled:               user.Enabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		PasswordChangedAt:     user.PasswordChangedAt,
+		Authorities:           authorities,
+	}
+}
+
+// Must be called with the lock held.
+func (m *Memory) deleteUserCredentials(username string) {
+	delete(m.authorities, username)
+	for id, apiKey := range m.apiKeys {
+		if apiKey.Username == username {
+			delete(m.apiKeys, id)
+		}
+	}
+	for key, identity := range m.identities {
+		if identity.Username == username {
+			delete(m.identities, key)
+		}
+	}
+	for id, session := range m.sessions {
+		if session.Username == username {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+func identityKey(issuer string, subject string) string {
+	return issuer + "\x00" + subject
+}
+
+func paginate[T any](items []T, limit int, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
@@ -0,0 +1,482 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+// newMemoryWithEntries creates the entries in order, so they get the ids 1, 2, ... like blog_entry_sequence.
+func newMemoryWithEntries(t *testing.T, authors ...string) *Memory {
+	t.Helper()
+	m := NewMemory()
+	for _, author := range authors {
+		if _, err := m.CreateBlogEntry(context.Background(), entities.BlogEntry{Title: "Title", Author: author}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func blogEntryIDs(blogEntries []entities.BlogEntry) []entities.BlogEntryID {
+	ids := []entities.BlogEntryID{}
+	for _, blogEntry := range blogEntries {
+		ids = append(ids, blogEntry.ID)
+	}
+	return ids
+}
+
+func TestMemoryGetBlogEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		offset  int
+		wantIDs []entities.BlogEntryID
+	}{
+		{name: "newest first", limit: 10, wantIDs: []entities.BlogEntryID{"3", "2", "1"}},
+		{name: "first page", limit: 2, wantIDs: []entities.BlogEntryID{"3", "2"}},
+		{name: "last page", limit: 2, offset: 2, wantIDs: []entities.BlogEntryID{"1"}},
+		{name: "past the end", limit: 2, offset: 3, wantIDs: []entities.BlogEntryID{}},
+	}
+	m := newMemoryWithEntries(t, "alice", "bob", "alice")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blogEntries, err := m.GetBlogEntries(context.Background(), tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := blogEntryIDs(blogEntries); !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestMemoryCreateBlogEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       entities.BlogEntryID
+		wantID   entities.BlogEntryID
+		wantKind apperror.Kind
+	}{
+		{name: "next id of the sequence", wantID: "2"},
+		{name: "given uuid", id: "01890a5d-ac96-774b-bcce-b302099a8057", wantID: "01890a5d-ac96-774b-bcce-b302099a8057"},
+		{name: "existing id", id: "1", wantKind: apperror.CONFLICT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMemoryWithEntries(t, "alice")
+			id, err := m.CreateBlogEntry(context.Background(), entities.BlogEntry{ID: tt.id, Title: "Title", Author: "bob"})
+			if tt.wantKind != 0 {
+				if apperror.KindOf(err) != tt.wantKind {
+					t.Fatalf("error = %v, want kind %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID {
+				t.Fatalf("id = %v, want %v", id, tt.wantID)
+			}
+			blogEntry, err := m.GetBlogEntryById(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blogEntry.Author != "bob" || blogEntry.CreatedAt.IsZero() {
+				t.Errorf("stored entry = %+v", blogEntry)
+			}
+		})
+	}
+}
+
+func TestMemoryGetBlogEntryByIdNotFound(t *testing.T) {
+	m := newMemoryWithEntries(t, "alice")
+	if _, err := m.GetBlogEntryById(context.Background(), "2"); apperror.KindOf(err) != apperror.NOT_FOUND {
+		t.Fatalf("error = %v, want kind %v", err, apperror.NOT_FOUND)
+	}
+}
+
+func TestMemoryUpdateUser(t *testing.T) {
+	audit := entities.AuditLogEntry{Action: "update"}
+	changedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		username  string
+		update    func(m *Memory, username string) (bool, error)
+		wantFound bool
+		want      dto.UserAccount
+		// Number of audit log entries written, an unknown user gets none.
+		wantAudit int
+	}{
+		{
+			name:     "disable",
+			username: "alice",
+			update: func(m *Memory, username string) (bool, error) {
+				return m.SetUserEnabled(context.Background(), username, false, audit)
+			},
+			wantFound: true,
+			want:      dto.UserAccount{Username: "alice", Enabled: false, Authorities: []string{}},
+			wantAudit: 1,
+		},
+		{
+			name:     "require password reset",
+			username: "alice",
+			update: func(m *Memory, username string) (bool, error) {
+				return m.RequirePasswordReset(context.Background(), username, audit)
+			},
+			wantFound: true,
+			want:      dto.UserAccount{Username: "alice", Enabled: true, PasswordResetRequired: true, Authorities: []string{}},
+			wantAudit: 1,
+		},
+		{
+			name:     "new password ends the required reset",
+			username: "alice",
+			update: func(m *Memory, username string) (bool, error) {
+				m.RequirePasswordReset(context.Background(), username, audit)
+				return m.UpdatePassword(context.Background(), username, "$2a$04$new", changedAt)
+			},
+			wantFound: true,
+			want:      dto.UserAccount{Username: "alice", Enabled: true, PasswordChangedAt: &changedAt, Authorities: []string{}},
+			wantAudit: 1,
+		},
+		{
+			name:     "rehash keeps the tokens",
+			username: "alice",
+			update: func(m *Memory, username string) (bool, error) {
+				return m.RehashPassword(context.Background(), username, "$argon2id$new")
+			},
+			wantFound: true,
+			want:      dto.UserAccount{Username: "alice", Enabled: true, Authorities: []string{}},
+		},
+		{
+			name:     "unknown user",
+			username: "bob",
+			update: func(m *Memory, username string) (bool, error) {
+				return m.SetUserEnabled(context.Background(), username, false, audit)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+			if err := m.RegisterUser(context.Background(), entities.User{Username: "alice", Password: "$2a$04$old"}); err != nil {
+				t.Fatal(err)
+			}
+			found, err := tt.update(m, tt.username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("found = %v, want %v", found, tt.wantFound)
+			}
+			if got := len(m.AuditLog()); got != tt.wantAudit {
+				t.Errorf("audit log entries = %v, want %v", got, tt.wantAudit)
+			}
+			if !found {
+				return
+			}
+			account, err := m.GetUserAccount(context.Background(), tt.username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if account.Username != tt.want.Username || account.Enabled != tt.want.Enabled ||
+				account.PasswordResetRequired != tt.want.PasswordResetRequired || !slices.Equal(account.Authorities, tt.want.Authorities) {
+				t.Errorf("account = %+v, want %+v", account, tt.want)
+			}
+			if !equalTimes(account.PasswordChangedAt, tt.want.PasswordChangedAt) {
+				t.Errorf("password changed at = %v, want %v", account.PasswordChangedAt, tt.want.PasswordChangedAt)
+			}
+		})
+	}
+}
+
+func equalTimes(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func TestMemoryRegisterUserConflict(t *testing.T) {
+	m := NewMemory()
+	user := entities.User{Username: "alice", Password: "$2a$04$hash"}
+	if err := m.RegisterUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterUser(context.Background(), user); apperror.KindOf(err) != apperror.CONFLICT {
+		t.Fatalf("error = %v, want kind %v", err, apperror.CONFLICT)
+	}
+}
+
+func TestMemorySearchUserAccounts(t *testing.T) {
+	tests := []struct {
+		search        string
+		pageNumber    int
+		pageSize      int
+		wantUsernames []string
+		wantTotal     int
+	}{
+		{search: "", pageNumber: 1, pageSize: 10, wantUsernames: []string{"Alice", "alina", "bob"}, wantTotal: 3},
+		{search: "AL", pageNumber: 1, pageSize: 10, wantUsernames: []string{"Alice", "alina"}, wantTotal: 2},
+		{search: "", pageNumber: 2, pageSize: 2, wantUsernames: []string{"bob"}, wantTotal: 3},
+		{search: "carol", pageNumber: 1, pageSize: 10, wantUsernames: []string{}, wantTotal: 0},
+	}
+	m := NewMemory()
+	for _, username := range []string{"bob", "alina", "Alice"} {
+		if err := m.RegisterUser(context.Background(), entities.User{Username: username}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			accounts, total, err := m.SearchUserAccounts(context.Background(), tt.search, tt.pageNumber, tt.pageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			usernames := []string{}
+			for _, account := range accounts {
+				usernames = append(usernames, account.Username)
+			}
+			if !slices.Equal(usernames, tt.wantUsernames) || total != tt.wantTotal {
+				t.Errorf("accounts = %v of %v, want %v of %v", usernames, total, tt.wantUsernames, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestMemoryDeleteAccount(t *testing.T) {
+	const alias = "deleted-user-1"
+	tests := []struct {
+		name        string
+		request     dto.AccountDeletionRequest
+		wantErr     bool
+		wantAuthors []string
+		wantAlias   bool
+	}{
+		{
+			name:        "delete user and entries",
+			request:     dto.AccountDeletionRequest{Mode: dto.ACCOUNT_DELETION_MODE_DELETE, Entries: dto.ENTRIES_POLICY_DELETE},
+			wantAuthors: []string{"bob"},
+		},
+		{
+			name:        "anonymize user and entries",
+			request:     dto.AccountDeletionRequest{Mode: dto.ACCOUNT_DELETION_MODE_ANONYMIZE, Entries: dto.ENTRIES_POLICY_ANONYMIZE},
+			wantAuthors: []string{alias, "bob", alias},
+			wantAlias:   true,
+		},
+		{
+			name: "reassign entries to an existing user",
+			request: dto.AccountDeletionRequest{Mode: dto.ACCOUNT_DELETION_MODE_DELETE,
+				Entries: dto.ENTRIES_POLICY_REASSIGN, ReassignTo: "bob"},
+			wantAuthors: []string{"bob", "bob", "bob"},
+		},
+		{
+			name: "reassign entries to an unknown user changes nothing",
+			request: dto.AccountDeletionRequest{Mode: dto.ACCOUNT_DELETION_MODE_DELETE,
+				Entries: dto.ENTRIES_POLICY_REASSIGN, ReassignTo: "carol"},
+			wantErr:     true,
+			wantAuthors: []string{"alice", "bob", "alice"},
+		},
+		{
+			name:        "unknown entries policy changes nothing",
+			request:     dto.AccountDeletionRequest{Mode: dto.ACCOUNT_DELETION_MODE_DELETE, Entries: "transfer"},
+			wantErr:     true,
+			wantAuthors: []string{"alice", "bob", "alice"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := newMemoryWithEntries(t, "alice", "bob", "alice")
+			for _, username := range []string{"alice", "bob"} {
+				if err := m.RegisterUser(ctx, entities.User{Username: username}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m.AddAuthority(ctx, entities.Authority{Username: "alice", Authority: "ROLE_ADMIN"}, entities.AuditLogEntry{})
+			m.CreateApiKey(ctx, entities.ApiKey{ID: "key-1", Username: "alice", KeyHash: "hash"})
+			m.CreateSession(ctx, entities.Session{ID: "session-1", Username: "alice"})
+
+			err := m.DeleteAccount(ctx, "alice", tt.request, alias, entities.AuditLogEntry{Action: "delete_account"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			blogEntries, _ := m.GetBlogEntries(ctx, 10, 0)
+			authors := []string{}
+			for _, blogEntry := range blogEntries {
+				authors = append(authors, blogEntry.Author)
+			}
+			if !slices.Equal(authors, tt.wantAuthors) {
+				t.Errorf("authors = %v, want %v", authors, tt.wantAuthors)
+			}
+			if tt.wantErr {
+				return
+			}
+			if account, _ := m.GetUserAccount(ctx, "alice"); account != nil {
+				t.Errorf("deleted user still has an account: %+v", account)
+			}
+			if apiKeys, _ := m.GetApiKeysByUsername(ctx, "alice"); len(apiKeys) != 0 {
+				t.Errorf("deleted user still has api keys: %v", apiKeys)
+			}
+			if sessions, _ := m.GetSessionsByUsername(ctx, "alice"); len(sessions) != 0 {
+				t.Errorf("deleted user still has sessions: %v", sessions)
+			}
+			aliasAccount, _ := m.GetUserAccount(ctx, alias)
+			if (aliasAccount != nil) != tt.wantAlias {
+				t.Errorf("alias account = %+v, want one %v", aliasAccount, tt.wantAlias)
+			}
+			if aliasAccount != nil && (aliasAccount.Enabled || len(aliasAccount.Authorities) != 0) {
+				t.Errorf("alias account = %+v, want it disabled without authorities", aliasAccount)
+			}
+			// The first entry was written when the authority was added.
+			if auditLog := m.AuditLog(); len(auditLog) != 2 || auditLog[1].Action != "delete_account" {
+				t.Errorf("audit log = %v, want the deletion after the grant", auditLog)
+			}
+		})
+	}
+}
+
+func TestMemoryUserIdentities(t *testing.T) {
+	const issuer = "https://idp.example.com"
+	tests := []struct {
+		name     string
+		link     func(m *Memory, identity entities.UserIdentity) error
+		identity entities.UserIdentity
+		wantKind apperror.Kind
+	}{
+		{
+			name: "create a user for a new identity",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.CreateUserWithIdentity(context.Background(), identity, "!")
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "new", Username: "carol"},
+		},
+		{
+			name: "create a user with a taken name",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.CreateUserWithIdentity(context.Background(), identity, "!")
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "new", Username: "alice"},
+			wantKind: apperror.CONFLICT,
+		},
+		{
+			name: "create a user for a linked identity",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.CreateUserWithIdentity(context.Background(), identity, "!")
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "linked", Username: "carol"},
+			wantKind: apperror.CONFLICT,
+		},
+		{
+			name: "link to an existing user",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.LinkUserIdentity(context.Background(), identity)
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "new", Username: "bob"},
+		},
+		{
+			name: "link to an unknown user",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.LinkUserIdentity(context.Background(), identity)
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "new", Username: "carol"},
+			wantKind: apperror.NOT_FOUND,
+		},
+		{
+			name: "link a linked identity",
+			link: func(m *Memory, identity entities.UserIdentity) error {
+				return m.LinkUserIdentity(context.Background(), identity)
+			},
+			identity: entities.UserIdentity{Issuer: issuer, Subject: "linked", Username: "bob"},
+			wantKind: apperror.CONFLICT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			m.RegisterUser(ctx, entities.User{Username: "bob"})
+			err := m.CreateUserWithIdentity(ctx, entities.UserIdentity{Issuer: issuer, Subject: "linked", Username: "alice"}, "!")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.identity.CreatedAt = time.Now()
+			err = tt.link(m, tt.identity)
+			identity, _ := m.GetUserIdentity(ctx, tt.identity.Issuer, tt.identity.Subject)
+			if tt.wantKind != 0 {
+				if apperror.KindOf(err) != tt.wantKind {
+					t.Fatalf("error = %v, want kind %v", err, tt.wantKind)
+				}
+				if identity != nil && identity.Username == tt.identity.Username {
+					t.Errorf("identity was linked to %v despite the error", identity.Username)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity == nil || identity.Username != tt.identity.Username {
+				t.Fatalf("identity = %+v, want it linked to %v", identity, tt.identity.Username)
+			}
+			if account, _ := m.GetUserAccount(ctx, tt.identity.Username); account == nil {
+				t.Errorf("user %v does not exist", tt.identity.Username)
+			}
+		})
+	}
+}
+
+func TestMemoryApiKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		id        string
+		wantFound bool
+		wantLeft  int
+	}{
+		{name: "revoke own key", username: "alice", id: "key-1", wantFound: true, wantLeft: 1},
+		{name: "revoke key of another user", username: "bob", id: "key-1", wantLeft: 2},
+		{name: "revoke unknown key", username: "alice", id: "key-3", wantLeft: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewMemory()
+			for _, id := range []string{"key-1", "key-2"} {
+				if err := m.CreateApiKey(ctx, entities.ApiKey{ID: id, Username: "alice", KeyHash: "hash-" + id}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			found, err := m.DeleteApiKey(ctx, tt.username, tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantFound {
+				t.Errorf("found = %v, want %v", found, tt.wantFound)
+			}
+			if apiKeys, _ := m.GetApiKeysByUsername(ctx, "alice"); len(apiKeys) != tt.wantLeft {
+				t.Errorf("api keys left = %v, want %v", len(apiKeys), tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestMemoryCreateApiKeyDuplicateHash(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if err := m.CreateApiKey(ctx, entities.ApiKey{ID: "key-1", Username: "alice", KeyHash: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateApiKey(ctx, entities.ApiKey{ID: "key-2", Username: "bob", KeyHash: "hash"}); err == nil {
+		t.Fatal("second api key with the same hash was created")
+	}
+	if _, err := m.GetApiKeyByHash(ctx, "unknown"); apperror.KindOf(err) != apperror.NOT_FOUND {
+		t.Fatalf("error = %v, want kind %v", err, apperror.NOT_FOUND)
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import "github.com/skyrenx/blog-api-go/http/metrics"
+
+var (
+	queryDuration = metrics.NewHistogram("db_query_duration_seconds",
+		"Duration of repository operations, including retries of their transaction.", metrics.DEFAULT_BUCKETS, "query")
+
+	connectionAcquireDuration = metrics.NewHistogram("db_connection_acquire_duration_seconds",
+		"Time waited for a connection of the pool, including connecting.", metrics.DEFAULT_BUCKETS)
+	connectionAcquireErrors = metrics.NewCounter("db_connection_acquire_errors_total",
+		"Connections that could not be acquired.")
+
+	dsqlTokenDuration = metrics.NewHistogram("dsql_token_generation_duration_seconds",
+		"Time taken to generate an Aurora DSQL auth token.", metrics.DEFAULT_BUCKETS)
+	dsqlTokens = metrics.NewCounter("dsql_token_requests_total",
+		"Auth tokens requested for new connections, by whether the cached token was used, a new one generated or generating failed.", "result")
+
+	transactions = metrics.NewCounter("db_transactions_total",
+		"Transactions started, retries not included.")
+	transactionRetries = metrics.NewCounter("db_transaction_retries_total",
+		"Attempts of transactions repeated after a retryable error.")
+	transactionsExhausted = metrics.NewCounter("db_transactions_exhausted_total",
+		"Transactions that failed with a retryable error on their last attempt.")
+)
+
+// Results of dsql_token_requests_total.
+const (
+	TOKEN_RESULT_CACHED    = "cached"
+	TOKEN_RESULT_GENERATED = "generated"
+	TOKEN_RESULT_ERROR     = "error"
+)
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/sql/migrations"
+)
+
+// Created outside of the migrations, it records which of them are applied.
+const createSchemaMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		dirty BOOLEAN NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (version)
+	)
+`
+
+// MigrationStatus is a migration of this build together with its state in the database.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// migrationDB is the part of a backend the Migrator needs, statements use $n placeholders.
+type migrationDB interface {
+	migrationsTableExists(ctx context.Context) (bool, error)
+	appliedMigrations(ctx context.Context) ([]appliedMigration, error)
+	exec(ctx context.Context, statement string, args ...any) error
+}
+
+// Migrator applies the embedded schema migrations of a backend.
+//
+// Every migration is recorded as dirty before its statements run and marked clean when all
+// of them succeeded. Statements don't share a transaction, because Aurora DSQL doesn't allow
+// more than one DDL statement in a transaction, so a failed migration leaves a dirty record
+// that must be resolved by hand before the Migrator continues.
+type Migrator struct {
+	dialect string
+	db      migrationDB
+	// Printf reports the progress, e.g. the statements of a dry run.
+	Printf func(format string, args ...any)
+}
+
+func newMigrator(dialect string, db migrationDB) *Migrator {
+	return &Migrator{dialect: dialect, db: db, Printf: func(format string, args ...any) {
+		fmt.Printf(format, args...)
+	}}
+}
+
+// CheckCurrent returns an error if a migration of this build is pending or dirty.
+func (m *Migrator) CheckCurrent(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.Dirty {
+			return fmt.Errorf("migration %04d is dirty", status.Version)
+		}
+		if !status.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%v migrations are pending", pending)
+	}
+	return nil
+}
+
+// Status lists the migrations of this build and whether they are applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	available, applied, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(available))
+	for i, migration := range available {
+		statuses[i] = MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].Dirty = record.Dirty
+			statuses[i].AppliedAt = &record.AppliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies all pending migrations in order and returns them.
+// With dryRun the statements are only reported and the database is not changed.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]migrations.Migration, error) {
+	available, applied, err := m.verified(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migrations.Migration
+	for _, migration := range available {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) > 0 && !dryRun {
+		if err := m.db.exec(ctx, createSchemaMigrationsTable); err != nil {
+			return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+	}
+
+	for _, migration := range pending {
+		m.Printf("Applying migration %v_%v\n", migration.Version, migration.Name)
+		if dryRun {
+			m.printStatements(migration.Up)
+			continue
+		}
+		query := `INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES ($1, $2, $3, TRUE, $4)`
+		if err := m.db.exec(ctx, query, migration.Version, migration.Name, migration.Checksum, time.Now().UTC()); err != nil {
+			return nil, fmt.Errorf("failed to record migration %v: %w", migration.Version, err)
+		}
+		if err := m.run(ctx, migration.Version, migration.Up); err != nil {
+			return nil, err
+		}
+		query = `UPDATE schema_migrations SET dirty = FALSE WHERE version = $1`
+		if err := m.db.exec(ctx, query, migration.Version); err != nil {
+			return nil, fmt.Errorf("failed to record migration %v: %w", migration.Version, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them.
+// With dryRun the statements are only reported and the database is not changed.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]migrations.Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+	available, applied, err := m.verified(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []migrations.Migration
+	for i := len(available) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := available[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		reverted = append(reverted, migration)
+
+		m.Printf("Reverting migration %v_%v\n", migration.Version, migration.Name)
+		if dryRun {
+			m.printStatements(migration.Down)
+			continue
+		}
+		query := `UPDATE schema_migrations SET dirty = TRUE WHERE version = $1`
+		if err := m.db.exec(ctx, query, migration.Version); err != nil {
+			return nil, fmt.Errorf("failed to record migration %v: %w", migration.Version, err)
+		}
+		if err := m.run(ctx, migration.Version, migration.Down); err != nil {
+			return nil, err
+		}
+		if err := m.db.exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+			return nil, fmt.Errorf("failed to record migration %v: %w", migration.Version, err)
+		}
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) run(ctx context.Context, version int64, script string) error {
+	for _, statement := range migrations.Statements(script) {
+		if err := m.db.exec(ctx, statement); err != nil {
+			return fmt.Errorf("migration %v failed and is marked dirty: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) printStatements(script string) {
+	for _, statement := range migrations.Statements(script) {
+		m.Printf("%v\n", statement)
+	}
+}
+
+// verified loads the migrations and refuses to continue if the database doesn't match this build.
+func (m *Migrator) verified(ctx context.Context) ([]migrations.Migration, map[int64]appliedMigration, error) {
+	available, applied, err := m.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byVersion := make(map[int64]migrations.Migration, len(available))
+	for _, migration := range available {
+		byVersion[migration.Version] = migration
+	}
+	for version, record := range applied {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, nil, fmt.Errorf("applied migration %v_%v is unknown to this build", version, record.Name)
+		}
+		if record.Dirty {
+			return nil, nil, fmt.Errorf("migration %v_%v is dirty, repair the schema and its row in schema_migrations", version, record.Name)
+		}
+		if record.Checksum != migration.Checksum {
+			return nil, nil, fmt.Errorf("checksum of applied migration %v_%v does not match, it was edited after it was applied", version, record.Name)
+		}
+	}
+	return available, applied, nil
+}
+
+func (m *Migrator) load(ctx context.Context) ([]migrations.Migration, map[int64]appliedMigration, error) {
+	available, err := migrations.Load(m.dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied := make(map[int64]appliedMigration)
+	exists, err := m.db.migrationsTableExists(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	if !exists {
+		return available, applied, nil
+	}
+	records, err := m.db.appliedMigrations(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return available, applied, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skyrenx/blog-api-go/sql/migrations"
+)
+
+// newTestMigrator returns the migrator of an empty SQLite database and the output it prints.
+func newTestMigrator(t *testing.T) (*SQLite, *Migrator, *strings.Builder) {
+	t.Helper()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "blog.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Close() })
+	output := &strings.Builder{}
+	migrator := s.Migrator()
+	migrator.Printf = func(format string, args ...any) { fmt.Fprintf(output, format, args...) }
+	return s, migrator, output
+}
+
+func tableExists(t *testing.T, s *SQLite, table string) bool {
+	t.Helper()
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`
+	if err := s.db.QueryRow(query, table).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	s, migrator, _ := newTestMigrator(t)
+	available, err := migrations.Load(migrations.DIALECT_SQLITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := available[len(available)-1]
+
+	applied, err := migrator.Up(ctx, false)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != len(available) {
+		t.Fatalf("applied %v migrations, want %v", len(applied), len(available))
+	}
+	if err := migrator.CheckCurrent(ctx); err != nil {
+		t.Errorf("CheckCurrent after Up = %v, want nil", err)
+	}
+	if applied, err := migrator.Up(ctx, false); err != nil || len(applied) != 0 {
+		t.Errorf("second Up applied %v, %v, want nothing", len(applied), err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != latest.Version {
+		t.Fatalf("reverted %v, want only %v", reverted, latest.Version)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if wantApplied := status.Version != latest.Version; status.Applied != wantApplied || status.Dirty {
+			t.Errorf("status of %v = %+v, want applied %v and clean", status.Version, status, wantApplied)
+		}
+	}
+	if err := migrator.CheckCurrent(ctx); err == nil {
+		t.Error("CheckCurrent after Down = nil, want a pending migration")
+	}
+
+	if applied, err := migrator.Up(ctx, false); err != nil || len(applied) != 1 || applied[0].Version != latest.Version {
+		t.Errorf("Up after Down applied %v, %v, want only %v", applied, err, latest.Version)
+	}
+	if _, err := migrator.Down(ctx, len(available), false); err != nil {
+		t.Fatalf("Down of all migrations failed: %v", err)
+	}
+	if tableExists(t, s, "users") {
+		t.Error("users still exists after reverting all migrations")
+	}
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	ctx := context.Background()
+	s, migrator, output := newTestMigrator(t)
+
+	pending, err := migrator.Up(ctx, true)
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(pending) == 0 {
+		t.Fatal("dry run reported no pending migrations")
+	}
+	if tableExists(t, s, "schema_migrations") || tableExists(t, s, "users") {
+		t.Error("dry run of Up changed the database")
+	}
+	if !strings.Contains(output.String(), "CREATE TABLE IF NOT EXISTS users (") {
+		t.Errorf("output = %q, want the statements", output.String())
+	}
+
+	if _, err := migrator.Up(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	output.Reset()
+	if _, err := migrator.Down(ctx, 1, true); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if err := migrator.CheckCurrent(ctx); err != nil {
+		t.Errorf("dry run of Down changed the database: %v", err)
+	}
+	if !strings.Contains(output.String(), "Reverting migration") {
+		t.Errorf("output = %q, want the reverted migration", output.String())
+	}
+}
+
+func TestMigratorRefusesChangedDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  string
+		wantErr string
+	}{
+		{
+			name:    "checksum mismatch",
+			change:  `UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`,
+			wantErr: "checksum of applied migration 1_create_schema does not match",
+		},
+		{
+			name:    "dirty record",
+			change:  `UPDATE schema_migrations SET dirty = TRUE WHERE version = 1`,
+			wantErr: "migration 1_create_schema is dirty",
+		},
+		{
+			name:    "unknown migration",
+			change:  `INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (9999, 'future', 'x', FALSE, CURRENT_TIMESTAMP)`,
+			wantErr: "applied migration 9999_future is unknown to this build",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, migrator, _ := newTestMigrator(t)
+			if _, err := migrator.Up(ctx, false); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.db.Exec(tt.change); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(ctx, false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Up error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := migrator.Down(ctx, 1, false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Down error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigratorFailedMigrationIsDirty(t *testing.T) {
+	ctx := context.Background()
+	s, migrator, _ := newTestMigrator(t)
+	// Migration 2 copies blog_entries into blog_entries_new, which fails if the table exists.
+	if _, err := s.db.Exec(`CREATE TABLE blog_entries_new (id INT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(ctx, false); err == nil || !strings.Contains(err.Error(), "migration 2 failed and is marked dirty") {
+		t.Fatalf("Up error = %v, want migration 2 to fail", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Applied || statuses[0].Dirty {
+		t.Errorf("status of 1 = %+v, want applied and clean", statuses[0])
+	}
+	if !statuses[1].Applied || !statuses[1].Dirty {
+		t.Errorf("status of 2 = %+v, want applied and dirty", statuses[1])
+	}
+	if err := migrator.CheckCurrent(ctx); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("CheckCurrent = %v, want a dirty migration", err)
+	}
+	if _, err := migrator.Up(ctx, false); err == nil || !strings.Contains(err.Error(), "is dirty") {
+		t.Errorf("second Up error = %v, want it to refuse the dirty migration", err)
+	}
+}
+
+func TestMigratorDownNeedsSteps(t *testing.T) {
+	_, migrator, _ := newTestMigrator(t)
+	if _, err := migrator.Down(context.Background(), 0, false); err == nil {
+		t.Error("Down(0) = nil, want an error")
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+)
+
+func (p *Postgres) CreateApiKey(ctx context.Context, apiKey entities.ApiKey) error {
+	ctx, cancel := p.queryContext(ctx, "CreateApiKey")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	query := `
+		INSERT INTO api_keys (id, username, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = conn.Exec(ctx, query, apiKey.ID, apiKey.Username, apiKey.Name, apiKey.Prefix,
+		apiKey.KeyHash, apiKey.Scopes, apiKey.CreatedAt, apiKey.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) GetApiKeysByUsername(ctx context.Context, username string) ([]entities.ApiKey, error) {
+	ctx, cancel := p.queryContext(ctx, "GetApiKeysByUsername")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM api_keys WHERE username = $1 ORDER BY created_at DESC`
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys of user: %v: %w", username, err)
+	}
+	defer rows.Close()
+	apiKeys, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.ApiKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	return apiKeys, nil
+}
+
+func (p *Postgres) GetApiKeyByHash(ctx context.Context, keyHash string) (*entities.ApiKey, error) {
+	ctx, cancel := p.queryContext(ctx, "GetApiKeyByHash")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM api_keys WHERE key_hash = $1`
+	rows, err := conn.Query(ctx, query, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key by hash: %w", err)
+	}
+	defer rows.Close()
+	apiKey, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[entities.ApiKey])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperror.NotFound("no api key found with the given hash")
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	return &apiKey, nil
+}
+
+func (p *Postgres) DeleteApiKey(ctx context.Context, username string, id string) (bool, error) {
+	ctx, cancel := p.queryContext(ctx, "DeleteApiKey")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	query := `DELETE FROM api_keys WHERE id = $1 AND username = $2`
+	tag, err := conn.Exec(ctx, query, id, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %v: %w", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (p *Postgres) UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	ctx, cancel := p.queryContext(ctx, "UpdateApiKeyLastUsed")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+	_, err = conn.Exec(ctx, query, lastUsedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update last used time of api key: %v: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skyrenx/blog-api-go/http/entities"
+)
+
+func (p *Postgres) CreateAuditLogEntry(ctx context.Context, entry entities.AuditLogEntry) error {
+	return p.inTransaction(ctx, "CreateAuditLogEntry", func(ctx context.Context, tx pgx.Tx) error {
+		return insertAuditLogEntry(ctx, tx, entry)
+	})
+}
+
+// insertAuditLogEntry records the entry as part of the transaction of the audited change.
+func insertAuditLogEntry(ctx context.Context, tx pgx.Tx, entry entities.AuditLogEntry) error {
+	query := `
+		INSERT INTO audit_log (id, actor, action, target, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.Exec(ctx, query, entry.ID, entry.Actor, entry.Action, entry.Target, entry.Details, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit log entry %v: %w", entry.Action, err)
+	}
+	return nil
+}
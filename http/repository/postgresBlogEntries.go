@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+func (p *Postgres) CountBlogEntries(ctx context.Context) (int, error) {
+	ctx, cancel := p.queryContext(ctx, "CountBlogEntries")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var totalRows int
+	query := `SELECT COUNT(*) FROM blog_entries`
+	if err := conn.QueryRow(ctx, query).Scan(&totalRows); err != nil {
+		return 0, fmt.Errorf("failed to count blog entries: %w", err)
+	}
+	return totalRows, nil
+}
+
+func (p *Postgres) GetBlogEntries(ctx context.Context, limit int, offset int) ([]entities.BlogEntry, error) {
+	return getBlogEntriesOrSummaries[entities.BlogEntry](ctx, p, "GetBlogEntries", limit, offset)
+}
+
+func (p *Postgres) GetBlogEntrySummaries(ctx context.Context, limit int, offset int) ([]dto.BlogEntrySummary, error) {
+	return getBlogEntriesOrSummaries[dto.BlogEntrySummary](ctx, p, "GetBlogEntrySummaries", limit, offset)
+}
+
+// name labels the metrics and the span of the query.
+func getBlogEntriesOrSummaries[T any](ctx context.Context, p *Postgres, name string, limit int, offset int) ([]T, error) {
+	ctx, cancel := p.queryContext(ctx, name)
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var instance T
+	// Use reflection to extract field names with "db" tags
+	columns := getDBFieldNames(instance)
+	query := fmt.Sprintf("SELECT %s FROM blog_entries ORDER BY created_at DESC LIMIT $1 OFFSET $2", strings.Join(columns, ", "))
+	rows, err := conn.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blog entries: %w", err)
+	}
+	defer rows.Close()
+
+	blogEntriesOrSummaries, err := pgx.CollectRows(rows, pgx.RowToStructByName[T])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	slog.DebugContext(ctx, "Got blog entries", "count", len(blogEntriesOrSummaries))
+	return blogEntriesOrSummaries, nil
+}
+
+func (p *Postgres) GetBlogEntriesByAuthor(ctx context.Context, author string) ([]entities.BlogEntry, error) {
+	ctx, cancel := p.queryContext(ctx, "GetBlogEntriesByAuthor")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM blog_entries WHERE author = $1 ORDER BY created_at DESC`
+	rows, err := conn.Query(ctx, query, author)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blog entries by author: %v: %w", author, err)
+	}
+	defer rows.Close()
+	blogEntries, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.BlogEntry])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	return blogEntries, nil
+}
+
+func (p *Postgres) GetBlogEntryById(ctx context.Context, id entities.BlogEntryID) (*entities.BlogEntry, error) {
+	ctx, cancel := p.queryContext(ctx, "GetBlogEntryById")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM blog_entries WHERE id = $1 `
+	rows, err := conn.Query(ctx, query, string(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row by id: %v: %w", id, err)
+	}
+	defer rows.Close()
+	blogEntry, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[entities.BlogEntry])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperror.NotFound("no blog entry found with id %v", id)
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	return &blogEntry, nil
+}
+
+func (p *Postgres) CreateBlogEntry(ctx context.Context, entry entities.BlogEntry) (entities.BlogEntryID, error) {
+	var id entities.BlogEntryID
+	err := p.inTransaction(ctx, "CreateBlogEntry", func(ctx context.Context, tx pgx.Tx) error {
+		id = entry.ID
+		// Step 1: Retrieve the current NextId value, unless the entry already has an ID
+		// Aurora Serverless v2 does not allow unqualified FOR UPDATE on tables without a strict equality predicate on the key.
+		if id == "" {
+			var nextId int64
+			err := tx.QueryRow(ctx, `
+				UPDATE blog_entry_sequence
+				SET next_id = next_id + 1
+				RETURNING next_id - 1
+			`).Scan(&nextId)
+			if err != nil {
+				return fmt.Errorf("failed to get next_id: %w", err)
+			}
+			id = entities.LegacyBlogEntryID(nextId)
+		}
+
+		// Step 2: Insert the new BlogEntry using the ID
+		query := `
+			INSERT INTO blog_entries (id, title, content, author, created_at, updated_at, published)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		_, err := tx.Exec(ctx, query, string(id), entry.Title, entry.Content, entry.Author, time.Now(), time.Now(), entry.Published)
+		if isUniqueViolation(err) {
+			return apperror.Conflict("blog entry already exists: %v", id).Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert blog entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skyrenx/blog-api-go/sql/migrations"
+)
+
+// Migrator returns the schema migrator of the database.
+func (p *Postgres) Migrator() *Migrator {
+	return newMigrator(migrations.DIALECT_POSTGRES, p)
+}
+
+func (p *Postgres) migrationsTableExists(ctx context.Context) (bool, error) {
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations')`
+	err = conn.QueryRow(ctx, query).Scan(&exists)
+	return exists, err
+}
+
+func (p *Postgres) appliedMigrations(ctx context.Context) ([]appliedMigration, error) {
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT version, name, checksum, dirty, applied_at FROM schema_migrations ORDER BY version`
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[appliedMigration])
+}
+
+func (p *Postgres) exec(ctx context.Context, statement string, args ...any) error {
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, statement, args...)
+	return err
+}
@@ -0,0 +1,453 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+const sessionColumns = `id, username, login_method, created_at, expires_at`
+
+const userAccountColumns = `username, enabled, COALESCE(password_reset_required, FALSE) AS password_reset_required, password_changed_at`
+
+func (p *Postgres) GetUserByUsername(ctx context.Context, username string) (*dto.UserWithoutPassword, error) {
+	ctx, cancel := p.queryContext(ctx, "GetUserByUsername")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT username, enabled FROM users WHERE username = $1 `
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row by username: %v: %w", username, err)
+	}
+	defer rows.Close()
+	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dto.UserWithoutPassword])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperror.NotFound("no user found with username %v", username)
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	return &user, nil
+}
+
+func (p *Postgres) GetUserWithPassword(ctx context.Context, username string) (*entities.User, error) {
+	ctx, cancel := p.queryContext(ctx, "GetUserWithPassword")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `
+		SELECT username, password, enabled, COALESCE(password_reset_required, FALSE) AS password_reset_required,
+			password_changed_at
+		FROM users WHERE username = $1
+	`
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row by username: %v: %w", username, err)
+	}
+	defer rows.Close()
+	user, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[entities.User])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	return &user, nil
+}
+
+func (p *Postgres) GetUserAccount(ctx context.Context, username string) (*dto.UserAccount, error) {
+	ctx, cancel := p.queryContext(ctx, "GetUserAccount")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT ` + userAccountColumns + ` FROM users WHERE username = $1`
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row by username: %v: %w", username, err)
+	}
+	defer rows.Close()
+	account, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[dto.UserAccount])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	accounts := []dto.UserAccount{account}
+	if err := collectAuthorities(ctx, conn, accounts); err != nil {
+		return nil, err
+	}
+	return &accounts[0], nil
+}
+
+func (p *Postgres) SearchUserAccounts(ctx context.Context, search string, pageNumber int, pageSize int) ([]dto.UserAccount, int, error) {
+	ctx, cancel := p.queryContext(ctx, "SearchUserAccounts")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+	var totalRows int
+	query := `SELECT COUNT(*) FROM users WHERE LOWER(username) LIKE $1`
+	if err := conn.QueryRow(ctx, query, pattern).Scan(&totalRows); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query = `SELECT ` + userAccountColumns + ` FROM users WHERE LOWER(username) LIKE $1
+		ORDER BY username LIMIT $2 OFFSET $3`
+	rows, err := conn.Query(ctx, query, pattern, pageSize, (pageNumber-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+	accounts, err := pgx.CollectRows(rows, pgx.RowToStructByName[dto.UserAccount])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	if err := collectAuthorities(ctx, conn, accounts); err != nil {
+		return nil, 0, err
+	}
+	return accounts, totalRows, nil
+}
+
+func (p *Postgres) RegisterUser(ctx context.Context, user entities.User) error {
+	ctx, cancel := p.queryContext(ctx, "RegisterUser")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// Insert user into the database
+	query := `INSERT INTO users (username, password, enabled) VALUES ($1, $2, $3)`
+	_, err = conn.Exec(ctx, query, user.Username, user.Password, true)
+	if isUniqueViolation(err) {
+		return apperror.Conflict("username is already taken: %v", user.Username).Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *Postgres) UpdatePassword(ctx context.Context, username string, hashedPassword string, changedAt time.Time) (bool, error) {
+	query := `UPDATE users SET password = $2, password_reset_required = FALSE, password_changed_at = $3 WHERE username = $1`
+	return p.updateUser(ctx, "UpdatePassword", username, query, hashedPassword, changedAt)
+}
+
+func (p *Postgres) RehashPassword(ctx context.Context, username string, hashedPassword string) (bool, error) {
+	return p.updateUser(ctx, "RehashPassword", username, `UPDATE users SET password = $2 WHERE username = $1`, hashedPassword)
+}
+
+func (p *Postgres) SetUserEnabled(ctx context.Context, username string, enabled bool, audit entities.AuditLogEntry) (bool, error) {
+	return p.updateUserWithAudit(ctx, "SetUserEnabled", username, audit, `UPDATE users SET enabled = $2 WHERE username = $1`, enabled)
+}
+
+func (p *Postgres) RequirePasswordReset(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	return p.updateUserWithAudit(ctx, "RequirePasswordReset", username, audit, `UPDATE users SET password_reset_required = TRUE WHERE username = $1`)
+}
+
+func (p *Postgres) AddAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	return p.inTransaction(ctx, "AddAuthority", func(ctx context.Context, tx pgx.Tx) error {
+		query := `INSERT INTO authorities (username, authority) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, query, authority.Username, authority.Authority); err != nil {
+			return fmt.Errorf("failed to add authority %v to user %v: %w", authority.Authority, authority.Username, err)
+		}
+		return insertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+func (p *Postgres) RemoveAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	return p.inTransaction(ctx, "RemoveAuthority", func(ctx context.Context, tx pgx.Tx) error {
+		query := `DELETE FROM authorities WHERE username = $1 AND authority = $2`
+		if _, err := tx.Exec(ctx, query, authority.Username, authority.Authority); err != nil {
+			return fmt.Errorf("failed to remove authority %v from user %v: %w", authority.Authority, authority.Username, err)
+		}
+		return insertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+func (p *Postgres) DeleteUser(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	var found bool
+	err := p.inTransaction(ctx, "DeleteUser", func(ctx context.Context, tx pgx.Tx) error {
+		if err := deleteUserCredentials(ctx, tx, username); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM users WHERE username = $1`, username)
+		if err != nil {
+			return fmt.Errorf("failed to delete user %v: %w", username, err)
+		}
+		if found = tag.RowsAffected() > 0; !found {
+			return nil
+		}
+		return insertAuditLogEntry(ctx, tx, audit)
+	})
+	return found, err
+}
+
+func (p *Postgres) DeleteAccount(ctx context.Context, username string, request dto.AccountDeletionRequest, alias string, audit entities.AuditLogEntry) error {
+	return p.inTransaction(ctx, "DeleteAccount", func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		switch request.Entries {
+		case dto.ENTRIES_POLICY_DELETE:
+			_, err = tx.Exec(ctx, `DELETE FROM blog_entries WHERE author = $1`, username)
+		case dto.ENTRIES_POLICY_REASSIGN:
+			var exists bool
+			err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, request.ReassignTo).Scan(&exists)
+			if err == nil && !exists {
+				err = apperror.Validation("reassign_to names no user: %v", request.ReassignTo)
+			}
+			if err == nil {
+				_, err = tx.Exec(ctx, `UPDATE blog_entries SET author = $2 WHERE author = $1`, username, request.ReassignTo)
+			}
+		case dto.ENTRIES_POLICY_ANONYMIZE:
+			_, err = tx.Exec(ctx, `UPDATE blog_entries SET author = $2 WHERE author = $1`, username, alias)
+		default:
+			err = fmt.Errorf("unknown entries policy: %v", request.Entries)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply entries policy %v of user %v: %w", request.Entries, username, err)
+		}
+
+		if err := deleteUserCredentials(ctx, tx, username); err != nil {
+			return err
+		}
+		if request.Mode == dto.ACCOUNT_DELETION_MODE_ANONYMIZE {
+			// The password is replaced with a value that is not a valid hash, so the row can never log in.
+			query := `UPDATE users SET username = $2, password = '!', enabled = FALSE WHERE username = $1`
+			_, err = tx.Exec(ctx, query, username, alias)
+		} else {
+			_, err = tx.Exec(ctx, `DELETE FROM users WHERE username = $1`, username)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %v user %v: %w", request.Mode, username, err)
+		}
+		return insertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+func (p *Postgres) GetUserIdentity(ctx context.Context, issuer string, subject string) (*entities.UserIdentity, error) {
+	ctx, cancel := p.queryContext(ctx, "GetUserIdentity")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM user_identities WHERE issuer = $1 AND subject = $2`
+	rows, err := conn.Query(ctx, query, issuer, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %v %v: %w", issuer, subject, err)
+	}
+	defer rows.Close()
+	identity, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[entities.UserIdentity])
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect row: %w", err)
+	}
+	return &identity, nil
+}
+
+func (p *Postgres) GetUserIdentitiesByUsername(ctx context.Context, username string) ([]entities.UserIdentity, error) {
+	ctx, cancel := p.queryContext(ctx, "GetUserIdentitiesByUsername")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT * FROM user_identities WHERE username = $1 ORDER BY created_at`
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities of user: %v: %w", username, err)
+	}
+	defer rows.Close()
+	identities, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.UserIdentity])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	return identities, nil
+}
+
+func (p *Postgres) CreateSession(ctx context.Context, session entities.Session) error {
+	ctx, cancel := p.queryContext(ctx, "CreateSession")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	query := `INSERT INTO sessions (id, username, login_method, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err = conn.Exec(ctx, query, session.ID, session.Username, session.LoginMethod, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session of user: %v: %w", session.Username, err)
+	}
+	return nil
+}
+
+func (p *Postgres) GetSessionsByUsername(ctx context.Context, username string) ([]entities.Session, error) {
+	ctx, cancel := p.queryContext(ctx, "GetSessionsByUsername")
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE username = $1 ORDER BY created_at`
+	rows, err := conn.Query(ctx, query, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions of user: %v: %w", username, err)
+	}
+	defer rows.Close()
+	sessions, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Session])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	return sessions, nil
+}
+
+func (p *Postgres) CreateUserWithIdentity(ctx context.Context, identity entities.UserIdentity, hashedPassword string) error {
+	return p.inTransaction(ctx, "CreateUserWithIdentity", func(ctx context.Context, tx pgx.Tx) error {
+		query := `INSERT INTO users (username, password, enabled) VALUES ($1, $2, $3)`
+		_, err := tx.Exec(ctx, query, identity.Username, hashedPassword, true)
+		if isUniqueViolation(err) {
+			return apperror.Conflict("username is already taken: %v", identity.Username).Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to provision user: %v: %w", identity.Username, err)
+		}
+		return insertUserIdentity(ctx, tx, identity)
+	})
+}
+
+func (p *Postgres) LinkUserIdentity(ctx context.Context, identity entities.UserIdentity) error {
+	return p.inTransaction(ctx, "LinkUserIdentity", func(ctx context.Context, tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, identity.Username).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check user: %v: %w", identity.Username, err)
+		}
+		if !exists {
+			return apperror.NotFound("no user found with username %v", identity.Username)
+		}
+		return insertUserIdentity(ctx, tx, identity)
+	})
+}
+
+func insertUserIdentity(ctx context.Context, tx pgx.Tx, identity entities.UserIdentity) error {
+	query := `INSERT INTO user_identities (issuer, subject, username, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := tx.Exec(ctx, query, identity.Issuer, identity.Subject, identity.Username, identity.CreatedAt)
+	if isUniqueViolation(err) {
+		return apperror.Conflict("identity is already linked to a user").Wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) updateUser(ctx context.Context, name string, username string, query string, args ...any) (bool, error) {
+	ctx, cancel := p.queryContext(ctx, name)
+	defer cancel()
+	conn, err := p.getConnection(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, query, append([]any{username}, args...)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user %v: %w", username, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// updateUserWithAudit is updateUser recording audit in the same transaction if the user exists.
+func (p *Postgres) updateUserWithAudit(ctx context.Context, name string, username string, audit entities.AuditLogEntry, query string, args ...any) (bool, error) {
+	var found bool
+	err := p.inTransaction(ctx, name, func(ctx context.Context, tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, query, append([]any{username}, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to update user %v: %w", username, err)
+		}
+		if found = tag.RowsAffected() > 0; !found {
+			return nil
+		}
+		return insertAuditLogEntry(ctx, tx, audit)
+	})
+	return found, err
+}
+
+// There are no foreign keys in Aurora DSQL, so rows referencing the user are removed explicitly.
+func deleteUserCredentials(ctx context.Context, tx pgx.Tx, username string) error {
+	for _, table := range []string{"authorities", "api_keys", "user_identities", "sessions"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE username = $1`, username); err != nil {
+			return fmt.Errorf("failed to delete %v of user %v: %w", table, username, err)
+		}
+	}
+	return nil
+}
+
+// collectAuthorities fills in the authorities of the accounts with a single query.
+func collectAuthorities(ctx context.Context, conn *pgxpool.Conn, accounts []dto.UserAccount) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	usernames := make([]string, len(accounts))
+	byUsername := make(map[string]*dto.UserAccount, len(accounts))
+	for i := range accounts {
+		usernames[i] = accounts[i].Username
+		accounts[i].Authorities = []string{}
+		byUsername[accounts[i].Username] = &accounts[i]
+	}
+
+	query := `SELECT username, authority FROM authorities WHERE username = ANY($1) ORDER BY authority`
+	rows, err := conn.Query(ctx, query, usernames)
+	if err != nil {
+		return fmt.Errorf("failed to get authorities: %w", err)
+	}
+	defer rows.Close()
+	authorities, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Authority])
+	if err != nil {
+		return fmt.Errorf("failed to collect rows: %w", err)
+	}
+	for _, authority := range authorities {
+		account := byUsername[authority.Username]
+		account.Authorities = append(account.Authorities, authority.Authority)
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,137 @@
+// Package repository is the data access layer. Services depend on the interfaces below,
+// the implementations live next to them. Every operation is bounded by the deadline of its
+// context and by the query timeout of config.DatabaseConfig.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+type BlogEntryRepository interface {
+	CountBlogEntries(ctx context.Context) (int, error)
+	// Entries are ordered by created_at, newest first.
+	GetBlogEntries(ctx context.Context, limit int, offset int) ([]entities.BlogEntry, error)
+	GetBlogEntrySummaries(ctx context.Context, limit int, offset int) ([]dto.BlogEntrySummary, error)
+	GetBlogEntriesByAuthor(ctx context.Context, author string) ([]entities.BlogEntry, error)
+	GetBlogEntryById(ctx context.Context, id entities.BlogEntryID) (*entities.BlogEntry, error)
+	// Inserts the entry with its ID, or with the next ID of the sequence if the ID is empty.
+	// Returns the id of the entry.
+	CreateBlogEntry(ctx context.Context, entry entities.BlogEntry) (entities.BlogEntryID, error)
+}
+
+type UserRepository interface {
+	GetUserByUsername(ctx context.Context, username string) (*dto.UserWithoutPassword, error)
+	// Returns nil if the user does not exist.
+	GetUserWithPassword(ctx context.Context, username string) (*entities.User, error)
+	// Returns nil if the user does not exist.
+	GetUserAccount(ctx context.Context, username string) (*dto.UserAccount, error)
+	// Returns a page of users whose username contains search, ordered by username,
+	// and the total number of matching users.
+	SearchUserAccounts(ctx context.Context, search string, pageNumber int, pageSize int) ([]dto.UserAccount, int, error)
+	// The password of the user must already be hashed.
+	RegisterUser(ctx context.Context, user entities.User) error
+	// Replaces the password hash, clears a forced password reset and records changedAt,
+	// which revokes the tokens issued before.
+	// Returns false if the user does not exist.
+	UpdatePassword(ctx context.Context, username string, hashedPassword string, changedAt time.Time) (bool, error)
+	// Replaces the password hash of an unchanged password, e.g. with a stronger hash.
+	// Returns false if the user does not exist.
+	RehashPassword(ctx context.Context, username string, hashedPassword string) (bool, error)
+	// The changes below are made atomically with their audit log entry.
+	// Returns false if the user does not exist.
+	SetUserEnabled(ctx context.Context, username string, enabled bool, audit entities.AuditLogEntry) (bool, error)
+	// Blocks logins and tokens of the user until the password is changed.
+	// Returns false if the user does not exist.
+	RequirePasswordReset(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error)
+	AddAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error
+	RemoveAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error
+	// Removes the user together with its authorities, api keys and linked identities.
+	// Returns false if the user does not exist.
+	DeleteUser(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error)
+	// Deletes or anonymizes the user as requested and applies the entries policy,
+	// atomically with the audit log entry.
+	// alias replaces the username in anonymized user rows and entries.
+	DeleteAccount(ctx context.Context, username string, request dto.AccountDeletionRequest, alias string, audit entities.AuditLogEntry) error
+
+	// Returns nil if no user is linked to the identity.
+	GetUserIdentity(ctx context.Context, issuer string, subject string) (*entities.UserIdentity, error)
+	GetUserIdentitiesByUsername(ctx context.Context, username string) ([]entities.UserIdentity, error)
+	// Creates the user named by the identity with the given password hash and links the identity to it.
+	// Returns a CONFLICT error if the username is taken or the identity is linked already.
+	CreateUserWithIdentity(ctx context.Context, identity entities.UserIdentity, hashedPassword string) error
+	// Links the identity to the existing user it names.
+	// Returns a NOT_FOUND error if the user does not exist and a CONFLICT error if the identity is linked already.
+	LinkUserIdentity(ctx context.Context, identity entities.UserIdentity) error
+
+	CreateSession(ctx context.Context, session entities.Session) error
+	// Sessions are ordered by created_at, oldest first.
+	GetSessionsByUsername(ctx context.Context, username string) ([]entities.Session, error)
+}
+
+type ApiKeyRepository interface {
+	CreateApiKey(ctx context.Context, apiKey entities.ApiKey) error
+	GetApiKeysByUsername(ctx context.Context, username string) ([]entities.ApiKey, error)
+	GetApiKeyByHash(ctx context.Context, keyHash string) (*entities.ApiKey, error)
+	// Returns false if the user has no api key with the given id.
+	DeleteApiKey(ctx context.Context, username string, id string) (bool, error)
+	UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error
+}
+
+type AuditLogRepository interface {
+	CreateAuditLogEntry(ctx context.Context, entry entities.AuditLogEntry) error
+}
+
+// Pinger checks that the database can be reached.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Repositories bundles one implementation of every repository.
+type Repositories struct {
+	BlogEntries BlogEntryRepository
+	Users       UserRepository
+	ApiKeys     ApiKeyRepository
+	AuditLog    AuditLogRepository
+	Database    Pinger
+	// Migrator manages the schema, nil for config.BACKEND_MEMORY which has none.
+	Migrator *Migrator
+}
+
+// New creates the repositories of a backend.
+// config.BACKEND_DSQL connects to the Aurora DSQL cluster with IAM authentication,
+// config.BACKEND_POSTGRES connects to any Postgres server with the credentials of the DSN,
+// config.BACKEND_SQLITE stores the data in a local file for single node deployments and
+// config.BACKEND_MEMORY keeps all data in memory and needs no database at all.
+func New(database config.DatabaseConfig) (*Repositories, error) {
+	switch database.Backend {
+	case config.BACKEND_DSQL:
+		dsql := NewDSQL(database.ClusterEndpoint, database.Region)
+		dsql.queryTimeout = database.QueryTimeout
+		return &Repositories{BlogEntries: dsql, Users: dsql, ApiKeys: dsql, AuditLog: dsql, Database: dsql, Migrator: dsql.Migrator()}, nil
+	case config.BACKEND_POSTGRES:
+		postgres, err := NewPostgres(database.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		postgres.queryTimeout = database.QueryTimeout
+		return &Repositories{BlogEntries: postgres, Users: postgres, ApiKeys: postgres, AuditLog: postgres, Database: postgres, Migrator: postgres.Migrator()}, nil
+	case config.BACKEND_SQLITE:
+		sqlite, err := NewSQLite(database.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		sqlite.queryTimeout = database.QueryTimeout
+		return &Repositories{BlogEntries: sqlite, Users: sqlite, ApiKeys: sqlite, AuditLog: sqlite, Database: sqlite, Migrator: sqlite.Migrator()}, nil
+	case config.BACKEND_MEMORY:
+		memory := NewMemory()
+		return &Repositories{BlogEntries: memory, Users: memory, ApiKeys: memory, AuditLog: memory, Database: memory}, nil
+	default:
+		return nil, fmt.Errorf("unknown database backend: %v", database.Backend)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// Attempts of a transaction, including the first one.
+	TRANSACTION_MAX_ATTEMPTS = 5
+	// The backoff doubles after every attempt, up to the maximum, and a random part of it is waited.
+	TRANSACTION_BASE_BACKOFF = 20 * time.Millisecond
+	TRANSACTION_MAX_BACKOFF  = 1 * time.Second
+)
+
+// SQLSTATEs of transactions aborted by a concurrent transaction, running them again may succeed.
+var retryableCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"OC000": true, // Aurora DSQL: mutation conflicts with another transaction
+	"OC001": true, // Aurora DSQL: schema was updated by another transaction
+}
+
+// isRetryable reports whether err aborted the transaction because of a concurrent transaction.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && retryableCodes[pgErr.Code]
+}
+
+// withRetry runs attempt until it succeeds, fails with an error that is not retryable,
+// or runs out of attempts. attempt must run a whole transaction, so that a retry starts from scratch.
+func withRetry(ctx context.Context, attempt func() error) error {
+	transactions.Inc()
+	backoff := TRANSACTION_BASE_BACKOFF
+	for i := 1; ; i++ {
+		err := attempt()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+		if i == TRANSACTION_MAX_ATTEMPTS {
+			transactionsExhausted.Inc()
+			slog.ErrorContext(ctx, "Transaction failed", "attempts", i, "error", err)
+			return err
+		}
+
+		transactionRetries.Inc()
+		// Full jitter spreads out the transactions that conflicted with each other.
+		wait := rand.N(backoff)
+		slog.WarnContext(ctx, "Retrying transaction", "wait", wait, "attempt", i, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("transaction not retried: %w", errors.Join(ctx.Err(), err))
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, TRANSACTION_MAX_BACKOFF)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, want: true},
+		{name: "dsql mutation conflict", err: &pgconn.PgError{Code: "OC000"}, want: true},
+		{name: "dsql schema conflict", err: &pgconn.PgError{Code: "OC001"}, want: true},
+		{name: "wrapped conflict", err: fmt.Errorf("failed to insert user: %w", &pgconn.PgError{Code: "OC000"}), want: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}},
+		{name: "not a database error", err: errors.New("40001")},
+		{name: "canceled", err: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	conflict := &pgconn.PgError{Code: "OC000"}
+	violation := &pgconn.PgError{Code: "23505"}
+	tests := []struct {
+		name string
+		// Errors of the attempts in order, nil once they are used up.
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "first attempt succeeds", wantAttempts: 1},
+		{name: "conflicts are retried", errs: []error{conflict, conflict}, wantAttempts: 3},
+		{name: "other errors are not retried", errs: []error{violation}, wantAttempts: 1, wantErr: violation},
+		{name: "error after a conflict", errs: []error{conflict, violation}, wantAttempts: 2, wantErr: violation},
+		{
+			name:         "attempts run out",
+			errs:         []error{conflict, conflict, conflict, conflict, conflict, conflict},
+			wantAttempts: TRANSACTION_MAX_ATTEMPTS,
+			wantErr:      conflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry(context.Background(), func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	err := withRetry(ctx, func() error {
+		attempts++
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if !errors.Is(err, context.Canceled) || !isRetryable(err) {
+		t.Errorf("error = %v, want the cancellation and the conflict", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %v, want 1", attempts)
+	}
+}
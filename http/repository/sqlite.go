@@ -0,0 +1,648 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/sql/migrations"
+	_ "modernc.org/sqlite"
+)
+
+// SQLite implements the repositories on a local SQLite database, for single node deployments.
+// The queries are the ones of Postgres, except where the dialects differ.
+type SQLite struct {
+	db           *sql.DB
+	queryTimeout time.Duration
+}
+
+var (
+	_ BlogEntryRepository = (*SQLite)(nil)
+	_ UserRepository      = (*SQLite)(nil)
+	_ ApiKeyRepository    = (*SQLite)(nil)
+	_ AuditLogRepository  = (*SQLite)(nil)
+)
+
+// NewSQLite opens the database file at path, creating it if needed.
+// The tables are created by the migrations of Migrator.
+func NewSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, fmt.Errorf("DATABASE_URL is not set")
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, a single connection avoids "database is locked" errors.
+	db.SetMaxOpenConns(1)
+	return &SQLite{db: db}, nil
+}
+
+const blogEntryColumns = `id, title, content, author, created_at, updated_at, published`
+
+func (s *SQLite) CountBlogEntries(ctx context.Context) (int, error) {
+	ctx, cancel := s.queryContext(ctx, "CountBlogEntries")
+	defer cancel()
+	var totalRows int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM blog_entries`).Scan(&totalRows); err != nil {
+		return 0, fmt.Errorf("failed to count blog entries: %w", err)
+	}
+	return totalRows, nil
+}
+
+func (s *SQLite) GetBlogEntries(ctx context.Context, limit int, offset int) ([]entities.BlogEntry, error) {
+	ctx, cancel := s.queryContext(ctx, "GetBlogEntries")
+	defer cancel()
+	query := `SELECT ` + blogEntryColumns + ` FROM blog_entries ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	return queryRows(ctx, s.db, scanBlogEntry, query, limit, offset)
+}
+
+func (s *SQLite) GetBlogEntrySummaries(ctx context.Context, limit int, offset int) ([]dto.BlogEntrySummary, error) {
+	ctx, cancel := s.queryContext(ctx, "GetBlogEntrySummaries")
+	defer cancel()
+	query := `SELECT id, title, author, created_at FROM blog_entries ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	return queryRows(ctx, s.db, func(rows *sql.Rows) (dto.BlogEntrySummary, error) {
+		var summary dto.BlogEntrySummary
+		err := rows.Scan(&summary.ID, &summary.Title, &summary.Author, &summary.CreatedAt)
+		return summary, err
+	}, query, limit, offset)
+}
+
+func (s *SQLite) GetBlogEntriesByAuthor(ctx context.Context, author string) ([]entities.BlogEntry, error) {
+	ctx, cancel := s.queryContext(ctx, "GetBlogEntriesByAuthor")
+	defer cancel()
+	query := `SELECT ` + blogEntryColumns + ` FROM blog_entries WHERE author = $1 ORDER BY created_at DESC`
+	return queryRows(ctx, s.db, scanBlogEntry, query, author)
+}
+
+func (s *SQLite) GetBlogEntryById(ctx context.Context, id entities.BlogEntryID) (*entities.BlogEntry, error) {
+	ctx, cancel := s.queryContext(ctx, "GetBlogEntryById")
+	defer cancel()
+	query := `SELECT ` + blogEntryColumns + ` FROM blog_entries WHERE id = $1`
+	blogEntry, err := queryRow(ctx, s.db, scanBlogEntry, query, string(id))
+	if err != nil {
+		return nil, err
+	}
+	if blogEntry == nil {
+		return nil, apperror.NotFound("no blog entry found with id %v", id)
+	}
+	return blogEntry, nil
+}
+
+func (s *SQLite) CreateBlogEntry(ctx context.Context, entry entities.BlogEntry) (entities.BlogEntryID, error) {
+	var id entities.BlogEntryID
+	err := s.inTransaction(ctx, "CreateBlogEntry", func(ctx context.Context, tx *sql.Tx) error {
+		id = entry.ID
+		if id == "" {
+			var nextId int64
+			err := tx.QueryRowContext(ctx, `UPDATE blog_entry_sequence SET next_id = next_id + 1 RETURNING next_id - 1`).Scan(&nextId)
+			if err != nil {
+				return fmt.Errorf("failed to get next_id: %w", err)
+			}
+			id = entities.LegacyBlogEntryID(nextId)
+		}
+		query := `
+			INSERT INTO blog_entries (id, title, content, author, created_at, updated_at, published)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`
+		now := time.Now()
+		_, err := tx.ExecContext(ctx, query, string(id), entry.Title, entry.Content, entry.Author, now, now, entry.Published)
+		if isUniqueViolation(err) {
+			return apperror.Conflict("blog entry already exists: %v", id).Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert blog entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *SQLite) GetUserByUsername(ctx context.Context, username string) (*dto.UserWithoutPassword, error) {
+	ctx, cancel := s.queryContext(ctx, "GetUserByUsername")
+	defer cancel()
+	query := `SELECT username, enabled FROM users WHERE username = $1`
+	user, err := queryRow(ctx, s.db, func(row *sql.Rows) (dto.UserWithoutPassword, error) {
+		var user dto.UserWithoutPassword
+		err := row.Scan(&user.Username, &user.Enabled)
+		return user, err
+	}, query, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, apperror.NotFound("no user found with username %v", username)
+	}
+	return user, nil
+}
+
+func (s *SQLite) GetUserWithPassword(ctx context.Context, username string) (*entities.User, error) {
+	ctx, cancel := s.queryContext(ctx, "GetUserWithPassword")
+	defer cancel()
+	query := `
+		SELECT username, password, enabled, COALESCE(password_reset_required, FALSE), password_changed_at
+		FROM users WHERE username = $1
+	`
+	return queryRow(ctx, s.db, func(row *sql.Rows) (entities.User, error) {
+		var user entities.User
+		err := row.Scan(&user.Username, &user.Password, &user.Enabled, &user.PasswordResetRequired, &user.PasswordChangedAt)
+		return user, err
+	}, query, username)
+}
+
+func (s *SQLite) GetUserAccount(ctx context.Context, username string) (*dto.UserAccount, error) {
+	ctx, cancel := s.queryContext(ctx, "GetUserAccount")
+	defer cancel()
+	query := `SELECT ` + userAccountColumns + ` FROM users WHERE username = $1`
+	account, err := queryRow(ctx, s.db, scanUserAccount, query, username)
+	if err != nil || account == nil {
+		return nil, err
+	}
+	accounts := []dto.UserAccount{*account}
+	if err := s.collectAuthorities(ctx, accounts); err != nil {
+		return nil, err
+	}
+	return &accounts[0], nil
+}
+
+func (s *SQLite) SearchUserAccounts(ctx context.Context, search string, pageNumber int, pageSize int) ([]dto.UserAccount, int, error) {
+	ctx, cancel := s.queryContext(ctx, "SearchUserAccounts")
+	defer cancel()
+	// Unlike Postgres, SQLite has no default escape character for LIKE.
+	pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+	var totalRows int
+	query := `SELECT COUNT(*) FROM users WHERE LOWER(username) LIKE $1 ESCAPE '\'`
+	if err := s.db.QueryRowContext(ctx, query, pattern).Scan(&totalRows); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query = `SELECT ` + userAccountColumns + ` FROM users WHERE LOWER(username) LIKE $1 ESCAPE '\'
+		ORDER BY username LIMIT $2 OFFSET $3`
+	accounts, err := queryRows(ctx, s.db, scanUserAccount, query, pattern, pageSize, (pageNumber-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.collectAuthorities(ctx, accounts); err != nil {
+		return nil, 0, err
+	}
+	return accounts, totalRows, nil
+}
+
+func (s *SQLite) RegisterUser(ctx context.Context, user entities.User) error {
+	ctx, cancel := s.queryContext(ctx, "RegisterUser")
+	defer cancel()
+	query := `INSERT INTO users (username, password, enabled) VALUES ($1, $2, $3)`
+	_, err := s.db.ExecContext(ctx, query, user.Username, user.Password, true)
+	if isUniqueViolation(err) {
+		return apperror.Conflict("username is already taken: %v", user.Username).Wrap(err)
+	}
+	return err
+}
+
+func (s *SQLite) UpdatePassword(ctx context.Context, username string, hashedPassword string, changedAt time.Time) (bool, error) {
+	query := `UPDATE users SET password = $2, password_reset_required = FALSE, password_changed_at = $3 WHERE username = $1`
+	return s.updateUser(ctx, "UpdatePassword", username, query, hashedPassword, changedAt)
+}
+
+func (s *SQLite) RehashPassword(ctx context.Context, username string, hashedPassword string) (bool, error) {
+	return s.updateUser(ctx, "RehashPassword", username, `UPDATE users SET password = $2 WHERE username = $1`, hashedPassword)
+}
+
+func (s *SQLite) SetUserEnabled(ctx context.Context, username string, enabled bool, audit entities.AuditLogEntry) (bool, error) {
+	return s.updateUserWithAudit(ctx, "SetUserEnabled", username, audit, `UPDATE users SET enabled = $2 WHERE username = $1`, enabled)
+}
+
+func (s *SQLite) RequirePasswordReset(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	return s.updateUserWithAudit(ctx, "RequirePasswordReset", username, audit, `UPDATE users SET password_reset_required = TRUE WHERE username = $1`)
+}
+
+func (s *SQLite) AddAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	return s.inTransaction(ctx, "AddAuthority", func(ctx context.Context, tx *sql.Tx) error {
+		query := `INSERT INTO authorities (username, authority) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, authority.Username, authority.Authority); err != nil {
+			return fmt.Errorf("failed to add authority %v to user %v: %w", authority.Authority, authority.Username, err)
+		}
+		return sqliteInsertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+func (s *SQLite) RemoveAuthority(ctx context.Context, authority entities.Authority, audit entities.AuditLogEntry) error {
+	return s.inTransaction(ctx, "RemoveAuthority", func(ctx context.Context, tx *sql.Tx) error {
+		query := `DELETE FROM authorities WHERE username = $1 AND authority = $2`
+		if _, err := tx.ExecContext(ctx, query, authority.Username, authority.Authority); err != nil {
+			return fmt.Errorf("failed to remove authority %v from user %v: %w", authority.Authority, authority.Username, err)
+		}
+		return sqliteInsertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+func (s *SQLite) DeleteUser(ctx context.Context, username string, audit entities.AuditLogEntry) (bool, error) {
+	var found bool
+	err := s.inTransaction(ctx, "DeleteUser", func(ctx context.Context, tx *sql.Tx) error {
+		if err := sqliteDeleteUserCredentials(ctx, tx, username); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE username = $1`, username)
+		if err != nil {
+			return fmt.Errorf("failed to delete user %v: %w", username, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if found = affected > 0; !found {
+			return nil
+		}
+		return sqliteInsertAuditLogEntry(ctx, tx, audit)
+	})
+	return found, err
+}
+
+func (s *SQLite) DeleteAccount(ctx context.Context, username string, request dto.AccountDeletionRequest, alias string, audit entities.AuditLogEntry) error {
+	return s.inTransaction(ctx, "DeleteAccount", func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		switch request.Entries {
+		case dto.ENTRIES_POLICY_DELETE:
+			_, err = tx.ExecContext(ctx, `DELETE FROM blog_entries WHERE author = $1`, username)
+		case dto.ENTRIES_POLICY_REASSIGN:
+			var exists bool
+			err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, request.ReassignTo).Scan(&exists)
+			if err == nil && !exists {
+				err = apperror.Validation("reassign_to names no user: %v", request.ReassignTo)
+			}
+			if err == nil {
+				_, err = tx.ExecContext(ctx, `UPDATE blog_entries SET author = $2 WHERE author = $1`, username, request.ReassignTo)
+			}
+		case dto.ENTRIES_POLICY_ANONYMIZE:
+			_, err = tx.ExecContext(ctx, `UPDATE blog_entries SET author = $2 WHERE author = $1`, username, alias)
+		default:
+			err = fmt.Errorf("unknown entries policy: %v", request.Entries)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply entries policy %v of user %v: %w", request.Entries, username, err)
+		}
+
+		if err := sqliteDeleteUserCredentials(ctx, tx, username); err != nil {
+			return err
+		}
+		if request.Mode == dto.ACCOUNT_DELETION_MODE_ANONYMIZE {
+			query := `UPDATE users SET username = $2, password = '!', enabled = FALSE WHERE username = $1`
+			_, err = tx.ExecContext(ctx, query, username, alias)
+		} else {
+			_, err = tx.ExecContext(ctx, `DELETE FROM users WHERE username = $1`, username)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %v user %v: %w", request.Mode, username, err)
+		}
+		return sqliteInsertAuditLogEntry(ctx, tx, audit)
+	})
+}
+
+const userIdentityColumns = `issuer, subject, username, created_at`
+
+func (s *SQLite) GetUserIdentity(ctx context.Context, issuer string, subject string) (*entities.UserIdentity, error) {
+	ctx, cancel := s.queryContext(ctx, "GetUserIdentity")
+	defer cancel()
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE issuer = $1 AND subject = $2`
+	return queryRow(ctx, s.db, scanUserIdentity, query, issuer, subject)
+}
+
+func (s *SQLite) GetUserIdentitiesByUsername(ctx context.Context, username string) ([]entities.UserIdentity, error) {
+	ctx, cancel := s.queryContext(ctx, "GetUserIdentitiesByUsername")
+	defer cancel()
+	query := `SELECT ` + userIdentityColumns + ` FROM user_identities WHERE username = $1 ORDER BY created_at`
+	return queryRows(ctx, s.db, scanUserIdentity, query, username)
+}
+
+func (s *SQLite) CreateSession(ctx context.Context, session entities.Session) error {
+	ctx, cancel := s.queryContext(ctx, "CreateSession")
+	defer cancel()
+	query := `INSERT INTO sessions (id, username, login_method, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.db.ExecContext(ctx, query, session.ID, session.Username, session.LoginMethod, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session of user: %v: %w", session.Username, err)
+	}
+	return nil
+}
+
+func (s *SQLite) GetSessionsByUsername(ctx context.Context, username string) ([]entities.Session, error) {
+	ctx, cancel := s.queryContext(ctx, "GetSessionsByUsername")
+	defer cancel()
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE username = $1 ORDER BY created_at`
+	return queryRows(ctx, s.db, scanSession, query, username)
+}
+
+func (s *SQLite) CreateUserWithIdentity(ctx context.Context, identity entities.UserIdentity, hashedPassword string) error {
+	return s.inTransaction(ctx, "CreateUserWithIdentity", func(ctx context.Context, tx *sql.Tx) error {
+		query := `INSERT INTO users (username, password, enabled) VALUES ($1, $2, $3)`
+		_, err := tx.ExecContext(ctx, query, identity.Username, hashedPassword, true)
+		if isUniqueViolation(err) {
+			return apperror.Conflict("username is already taken: %v", identity.Username).Wrap(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to provision user: %v: %w", identity.Username, err)
+		}
+		return sqliteInsertUserIdentity(ctx, tx, identity)
+	})
+}
+
+func (s *SQLite) LinkUserIdentity(ctx context.Context, identity entities.UserIdentity) error {
+	return s.inTransaction(ctx, "LinkUserIdentity", func(ctx context.Context, tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, identity.Username).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check user: %v: %w", identity.Username, err)
+		}
+		if !exists {
+			return apperror.NotFound("no user found with username %v", identity.Username)
+		}
+		return sqliteInsertUserIdentity(ctx, tx, identity)
+	})
+}
+
+func sqliteInsertUserIdentity(ctx context.Context, tx *sql.Tx, identity entities.UserIdentity) error {
+	query := `INSERT INTO user_identities (issuer, subject, username, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := tx.ExecContext(ctx, query, identity.Issuer, identity.Subject, identity.Username, identity.CreatedAt)
+	if isUniqueViolation(err) {
+		return apperror.Conflict("identity is already linked to a user").Wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+const apiKeyColumns = `id, username, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at`
+
+func (s *SQLite) CreateApiKey(ctx context.Context, apiKey entities.ApiKey) error {
+	ctx, cancel := s.queryContext(ctx, "CreateApiKey")
+	defer cancel()
+	query := `
+		INSERT INTO api_keys (id, username, name, prefix, key_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := s.db.ExecContext(ctx, query, apiKey.ID, apiKey.Username, apiKey.Name, apiKey.Prefix,
+		apiKey.KeyHash, apiKey.Scopes, apiKey.CreatedAt, apiKey.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) GetApiKeysByUsername(ctx context.Context, username string) ([]entities.ApiKey, error) {
+	ctx, cancel := s.queryContext(ctx, "GetApiKeysByUsername")
+	defer cancel()
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE username = $1 ORDER BY created_at DESC`
+	return queryRows(ctx, s.db, scanApiKey, query, username)
+}
+
+func (s *SQLite) GetApiKeyByHash(ctx context.Context, keyHash string) (*entities.ApiKey, error) {
+	ctx, cancel := s.queryContext(ctx, "GetApiKeyByHash")
+	defer cancel()
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	apiKey, err := queryRow(ctx, s.db, scanApiKey, query, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, apperror.NotFound("no api key found with the given hash")
+	}
+	return apiKey, nil
+}
+
+func (s *SQLite) DeleteApiKey(ctx context.Context, username string, id string) (bool, error) {
+	ctx, cancel := s.queryContext(ctx, "DeleteApiKey")
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1 AND username = $2`, id, username)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete api key: %v: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// updateUserWithAudit is updateUser recording audit in the same transaction if the user exists.
+func (s *SQLite) updateUserWithAudit(ctx context.Context, name string, username string, audit entities.AuditLogEntry, query string, args ...any) (bool, error) {
+	var found bool
+	err := s.inTransaction(ctx, name, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, append([]any{username}, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to update user %v: %w", username, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if found = affected > 0; !found {
+			return nil
+		}
+		return sqliteInsertAuditLogEntry(ctx, tx, audit)
+	})
+	return found, err
+}
+
+func (s *SQLite) UpdateApiKeyLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	ctx, cancel := s.queryContext(ctx, "UpdateApiKeyLastUsed")
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, lastUsedAt, id); err != nil {
+		return fmt.Errorf("failed to update last used time of api key: %v: %w", id, err)
+	}
+	return nil
+}
+
+func (s *SQLite) CreateAuditLogEntry(ctx context.Context, entry entities.AuditLogEntry) error {
+	return s.inTransaction(ctx, "CreateAuditLogEntry", func(ctx context.Context, tx *sql.Tx) error {
+		return sqliteInsertAuditLogEntry(ctx, tx, entry)
+	})
+}
+
+// Migrator returns the schema migrator of the database.
+// Ping checks that the database file can be read.
+func (s *SQLite) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+func (s *SQLite) Migrator() *Migrator {
+	return newMigrator(migrations.DIALECT_SQLITE, s)
+}
+
+func (s *SQLite) migrationsTableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`
+	err := s.db.QueryRowContext(ctx, query).Scan(&exists)
+	return exists, err
+}
+
+func (s *SQLite) appliedMigrations(ctx context.Context) ([]appliedMigration, error) {
+	query := `SELECT version, name, checksum, dirty, applied_at FROM schema_migrations ORDER BY version`
+	return queryRows(ctx, s.db, func(rows *sql.Rows) (appliedMigration, error) {
+		var record appliedMigration
+		err := rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.Dirty, &record.AppliedAt)
+		return record, err
+	}, query)
+}
+
+func (s *SQLite) exec(ctx context.Context, statement string, args ...any) error {
+	_, err := s.db.ExecContext(ctx, statement, args...)
+	return err
+}
+
+func (s *SQLite) inTransaction(ctx context.Context, query string, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := s.queryContext(ctx, query)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback on error
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) updateUser(ctx context.Context, name string, username string, query string, args ...any) (bool, error) {
+	ctx, cancel := s.queryContext(ctx, name)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, query, append([]any{username}, args...)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update user %v: %w", username, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// collectAuthorities fills in the authorities of the accounts with a single query.
+// SQLite has no arrays, so the usernames are passed as a list of parameters instead of ANY($1).
+func (s *SQLite) collectAuthorities(ctx context.Context, accounts []dto.UserAccount) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(accounts))
+	usernames := make([]any, len(accounts))
+	byUsername := make(map[string]*dto.UserAccount, len(accounts))
+	for i := range accounts {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		usernames[i] = accounts[i].Username
+		accounts[i].Authorities = []string{}
+		byUsername[accounts[i].Username] = &accounts[i]
+	}
+
+	query := `SELECT username, authority FROM authorities WHERE username IN (` +
+		strings.Join(placeholders, ", ") + `) ORDER BY authority`
+	authorities, err := queryRows(ctx, s.db, func(rows *sql.Rows) (entities.Authority, error) {
+		var authority entities.Authority
+		err := rows.Scan(&authority.Username, &authority.Authority)
+		return authority, err
+	}, query, usernames...)
+	if err != nil {
+		return fmt.Errorf("failed to get authorities: %w", err)
+	}
+	for _, authority := range authorities {
+		account := byUsername[authority.Username]
+		account.Authorities = append(account.Authorities, authority.Authority)
+	}
+	return nil
+}
+
+func sqliteDeleteUserCredentials(ctx context.Context, tx *sql.Tx, username string) error {
+	for _, table := range []string{"authorities", "api_keys", "user_identities", "sessions"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE username = $1`, username); err != nil {
+			return fmt.Errorf("failed to delete %v of user %v: %w", table, username, err)
+		}
+	}
+	return nil
+}
+
+func sqliteInsertAuditLogEntry(ctx context.Context, tx *sql.Tx, entry entities.AuditLogEntry) error {
+	query := `
+		INSERT INTO audit_log (id, actor, action, target, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.ExecContext(ctx, query, entry.ID, entry.Actor, entry.Action, entry.Target, entry.Details, entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit log entry %v: %w", entry.Action, err)
+	}
+	return nil
+}
+
+func scanBlogEntry(rows *sql.Rows) (entities.BlogEntry, error) {
+	var blogEntry entities.BlogEntry
+	err := rows.Scan(&blogEntry.ID, &blogEntry.Title, &blogEntry.Content, &blogEntry.Author,
+		&blogEntry.CreatedAt, &blogEntry.UpdatedAt, &blogEntry.Published)
+	return blogEntry, err
+}
+
+func scanUserAccount(rows *sql.Rows) (dto.UserAccount, error) {
+	var account dto.UserAccount
+	err := rows.Scan(&account.Username, &account.Enabled, &account.PasswordResetRequired, &account.PasswordChangedAt)
+	return account, err
+}
+
+func scanUserIdentity(rows *sql.Rows) (entities.UserIdentity, error) {
+	var identity entities.UserIdentity
+	err := rows.Scan(&identity.Issuer, &identity.Subject, &identity.Username, &identity.CreatedAt)
+	return identity, err
+}
+
+func scanSession(rows *sql.Rows) (entities.Session, error) {
+	var session entities.Session
+	err := rows.Scan(&session.ID, &session.Username, &session.LoginMethod, &session.CreatedAt, &session.ExpiresAt)
+	return session, err
+}
+
+func scanApiKey(rows *sql.Rows) (entities.ApiKey, error) {
+	var apiKey entities.ApiKey
+	err := rows.Scan(&apiKey.ID, &apiKey.Username, &apiKey.Name, &apiKey.Prefix, &apiKey.KeyHash,
+		&apiKey.Scopes, &apiKey.CreatedAt, &apiKey.ExpiresAt, &apiKey.LastUsedAt)
+	return apiKey, err
+}
+
+func queryRows[T any](ctx context.Context, db *sql.DB, scan func(*sql.Rows) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+	items := []T{}
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect rows: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to collect rows: %w", err)
+	}
+	return items, nil
+}
+
+// queryRow returns nil if the query has no result.
+func queryRow[T any](ctx context.Context, db *sql.DB, scan func(*sql.Rows) (T, error), query string, args ...any) (*T, error) {
+	items, err := queryRows(ctx, db, scan, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if len(items) > 1 {
+		return nil, errors.New("failed to collect row: too many rows")
+	}
+	return &items[0], nil
+}
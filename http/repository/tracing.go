@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME = "github.com/skyrenx/blog-api-go/http/repository"
+
+var tracer = otel.Tracer(TRACER_NAME)
+
+// pgxTracer creates a span for every statement, connect and acquire of a pool.
+// Statements are recorded with their parameters as placeholders, never with their values.
+type pgxTracer struct{}
+
+var (
+	_ pgx.QueryTracer       = pgxTracer{}
+	_ pgx.ConnectTracer     = pgxTracer{}
+	_ pgxpool.AcquireTracer = pgxTracer{}
+)
+
+func (pgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := "query"
+	if fields := strings.Fields(data.SQL); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	ctx, _ = tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(data.SQL),
+	))
+	return ctx
+}
+
+func (pgxTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+func (pgxTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "connect", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.ServerAddress(data.ConnConfig.Host),
+	))
+	return ctx
+}
+
+func (pgxTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+func (pgxTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "acquire connection")
+	return ctx
+}
+
+func (pgxTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	endSpan(trace.SpanFromContext(ctx), data.Err)
+}
+
+// endSpan ends span as failed if err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
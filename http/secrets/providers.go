@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// FileProvider reads a secret from the file of its name, e.g. a mounted Docker or Kubernetes secret.
+// A trailing line break is removed.
+type FileProvider struct{}
+
+func (FileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// awsSession creates the session of the AWS providers on first use,
+// so the api can run without AWS credentials when no AWS secret is referenced.
+type awsSession struct {
+	region string
+
+	once    sync.Once
+	session *session.Session
+	err     error
+}
+
+func (s *awsSession) get() (*session.Session, error) {
+	s.once.Do(func() {
+		s.session, s.err = session.NewSession(&aws.Config{Region: aws.String(s.region)})
+	})
+	return s.session, s.err
+}
+
+// SSM reads parameters of SSM Parameter Store, SecureString parameters are decrypted.
+type SSM struct {
+	session awsSession
+
+	once   sync.Once
+	client *ssm.SSM
+}
+
+func NewSSM(region string) *SSM {
+	return &SSM{session: awsSession{region: region}}
+}
+
+func (p *SSM) GetSecret(ctx context.Context, name string) (string, error) {
+	sess, err := p.session.get()
+	if err != nil {
+		return "", err
+	}
+	p.once.Do(func() { p.client = ssm.New(sess) })
+	output, err := p.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.Parameter.Value), nil
+}
+
+// SecretsManager reads secrets of Secrets Manager. A name may end with "#field"
+// to take a single field of a secret whose value is a JSON object.
+type SecretsManager struct {
+	session awsSession
+
+	once   sync.Once
+	client *secretsmanager.SecretsManager
+}
+
+func NewSecretsManager(region string) *SecretsManager {
+	return &SecretsManager{session: awsSession{region: region}}
+}
+
+func (p *SecretsManager) GetSecret(ctx context.Context, name string) (string, error) {
+	sess, err := p.session.get()
+	if err != nil {
+		return "", err
+	}
+	p.once.Do(func() { p.client = secretsmanager.New(sess) })
+	id, field, hasField := strings.Cut(name, "#")
+	output, err := p.client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	value := aws.StringValue(output.SecretString)
+	if !hasField {
+		return value, nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	fieldValue, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %v", field)
+	}
+	if s, ok := fieldValue.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(fieldValue), nil
+}
@@ -0,0 +1,147 @@
+// Package secrets resolves configuration values that refer to a secret instead of containing it.
+//
+// A reference names the provider and the secret, e.g. "ssm:/blog/jwt" for a parameter of
+// SSM Parameter Store, "secretsmanager:blog/jwt" or "secretsmanager:blog/db#password" for a
+// secret of Secrets Manager or a field of its JSON value, and "file:/run/secrets/jwt" for a local file.
+// Any other value is used as it is.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SCHEME_SSM             = "ssm"
+	SCHEME_SECRETS_MANAGER = "secretsmanager"
+	SCHEME_FILE            = "file"
+
+	// Limit of fetching a secret in the background.
+	REFRESH_TIMEOUT = 10 * time.Second
+)
+
+// Provider fetches the current value of a secret by its name.
+type Provider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// Resolver resolves references with its providers and caches the secrets,
+// so every reference is fetched only once and then refreshed periodically.
+type Resolver struct {
+	providers       map[string]Provider
+	refreshInterval time.Duration
+
+	mu      sync.Mutex
+	secrets map[string]*Secret
+}
+
+// NewResolver creates a resolver with the SSM, Secrets Manager and file providers.
+// The AWS providers use region and connect only when they are first used.
+// Secrets are fetched again once they are older than refreshInterval, never if it is 0.
+func NewResolver(region string, refreshInterval time.Duration) *Resolver {
+	r := &Resolver{providers: make(map[string]Provider), refreshInterval: refreshInterval, secrets: make(map[string]*Secret)}
+	r.Register(SCHEME_SSM, NewSSM(region))
+	r.Register(SCHEME_SECRETS_MANAGER, NewSecretsManager(region))
+	r.Register(SCHEME_FILE, FileProvider{})
+	return r
+}
+
+// Register makes provider resolve the references starting with scheme and a colon.
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scheme] = provider
+}
+
+// Resolve fetches the secret that value refers to, or returns value itself if it is no reference.
+func (r *Resolver) Resolve(ctx context.Context, value string) (*Secret, error) {
+	scheme, name, found := strings.Cut(value, ":")
+	r.mu.Lock()
+	provider, ok := r.providers[scheme]
+	cached := r.secrets[value]
+	r.mu.Unlock()
+	if !found || !ok {
+		return Static(value), nil
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	secret, err := provider.GetSecret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %v: %w", value, err)
+	}
+	resolved := &Secret{reference: value, name: name, provider: provider, refreshInterval: r.refreshInterval,
+		value: secret, fetchedAt: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached := r.secrets[value]; cached != nil {
+		return cached, nil
+	}
+	r.secrets[value] = resolved
+	return resolved, nil
+}
+
+// Secret holds the value of a secret. The value is refreshed in the background
+// once it is older than the refresh interval, so a rotated secret is picked up
+// without blocking requests. If refreshing fails, the last value is kept.
+type Secret struct {
+	reference       string
+	name            string
+	provider        Provider
+	refreshInterval time.Duration
+
+	mu         sync.Mutex
+	value      string
+	previous   string
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// Static returns a secret that always has value.
+func Static(value string) *Secret {
+	return &Secret{value: value}
+}
+
+// Value returns the current value of the secret.
+func (s *Secret) Value() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil && s.refreshInterval > 0 && !s.refreshing && time.Since(s.fetchedAt) >= s.refreshInterval {
+		s.refreshing = true
+		go s.refresh()
+	}
+	return s.value
+}
+
+// Previous returns the value the secret had before it was last rotated, or "" if it never changed.
+// It allows to accept what was signed with the old value during a rotation.
+func (s *Secret) Previous() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.previous
+}
+
+func (s *Secret) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), REFRESH_TIMEOUT)
+	defer cancel()
+	value, err := s.provider.GetSecret(ctx, s.name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	// A failed refresh is retried after the next interval.
+	s.fetchedAt = time.Now()
+	if err != nil {
+		slog.Warn("Failed to refresh secret, keeping the last value", "reference", s.reference, "error", err)
+		return
+	}
+	if value != s.value {
+		slog.Info("Secret was rotated", "reference", s.reference)
+		s.previous, s.value = s.value, value
+	}
+}
@@ -0,0 +1,103 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+)
+
+// Lifetime of the tokens if config.TokenConfig has none.
+const DEFAULT_TOKEN_LIFETIME = 24 * time.Hour
+
+// Tokens issues and verifies the JWTs of the api.
+type Tokens struct {
+	lifetime time.Duration
+	secret   *secrets.Secret
+}
+
+// NewTokens signs tokens with secret, the resolved Secret of tokenConfig.
+// When the secret is rotated, tokens signed with its previous value stay valid until they expire.
+func NewTokens(tokenConfig config.TokenConfig, secret *secrets.Secret) *Tokens {
+	lifetime := tokenConfig.Lifetime
+	if lifetime == 0 {
+		lifetime = DEFAULT_TOKEN_LIFETIME
+	}
+	return &Tokens{lifetime: lifetime, secret: secret}
+}
+
+// GenerateJWT returns a new token of the user and its claims.
+// Every token gets a random ID, which names the session it starts.
+func (t *Tokens) GenerateJWT(username string) (string, *entities.Claims, error) {
+	jwtSecret := t.secret.Value()
+	if jwtSecret == "" {
+		return "", nil, errors.New("JWT_SECRET is not set")
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+
+	// The issue time lets changing the password revoke the token.
+	claims := &entities.Claims{
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.lifetime)),
+		},
+	}
+
+	// Create token with claims
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	// Sign the token with the secret key
+	signed, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// ParseJWT verifies a token created by GenerateJWT and returns its claims.
+func (t *Tokens) ParseJWT(tokenString string) (*entities.Claims, error) {
+	jwtSecret := t.secret.Value()
+	if jwtSecret == "" {
+		return nil, errors.New("JWT_SECRET is not set")
+	}
+	claims := &entities.Claims{}
+	err := parseJWT(tokenString, claims, jwtSecret)
+	if previous := t.secret.Previous(); errors.Is(err, jwt.ErrTokenSignatureInvalid) && previous != "" {
+		claims = &entities.Claims{}
+		err = parseJWT(tokenString, claims, previous)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Username == "" {
+		return nil, errors.New("invalid token: missing username")
+	}
+	return claims, nil
+}
+
+func parseJWT(tokenString string, claims *entities.Claims, jwtSecret string) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	return err
+}
+
+// DeriveKey returns a key for purpose derived from the secret,
+// so that what is signed with it can never be accepted as an api token.
+func (t *Tokens) DeriveKey(purpose string) []byte {
+	key := sha256.Sum256([]byte(purpose + ":" + t.secret.Value()))
+	return key[:]
+}
@@ -0,0 +1,148 @@
+// Package security hashes passwords and enforces the password policy.
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/skyrenx/blog-api-go/http/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// https://cheatsheetseries.owasp.org/cheatsheets/Password_Storage_Cheat_Sheet.html#argon2id
+	ARGON2_MEMORY      = 19 * 1024 // KiB
+	ARGON2_ITERATIONS  = 2
+	ARGON2_PARALLELISM = 1
+	ARGON2_SALT_LENGTH = 16
+	ARGON2_KEY_LENGTH  = 32
+)
+
+// withDefaults fills the settings missing in c: bcrypt with bcrypt.DefaultCost,
+// DEFAULT_PASSWORD_MIN_LENGTH and DEFAULT_PASSWORD_MAX_LENGTH.
+func withDefaults(c config.PasswordConfig) config.PasswordConfig {
+	if c.HashAlgorithm == "" {
+		c.HashAlgorithm = config.ALGORITHM_BCRYPT
+	}
+	if c.BcryptCost == 0 {
+		c.BcryptCost = bcrypt.DefaultCost
+	}
+	if c.MinLength == 0 {
+		c.MinLength = DEFAULT_PASSWORD_MIN_LENGTH
+	}
+	if c.MaxLength == 0 {
+		c.MaxLength = DEFAULT_PASSWORD_MAX_LENGTH
+	}
+	return c
+}
+
+// Passwords hashes passwords and enforces the password policy of its config.
+type Passwords struct {
+	config            config.PasswordConfig
+	breachedPasswords map[string]struct{}
+}
+
+// NewPasswords reads the list of breached passwords of passwordConfig, if any.
+func NewPasswords(passwordConfig config.PasswordConfig) (*Passwords, error) {
+	breachedPasswords, err := loadBreachedPasswords(passwordConfig.BreachedPasswordList)
+	if err != nil {
+		return nil, err
+	}
+	return &Passwords{config: withDefaults(passwordConfig), breachedPasswords: breachedPasswords}, nil
+}
+
+// HashPassword hashes a password with the configured algorithm.
+func (p *Passwords) HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password cannot be empty")
+	}
+	switch algorithm := p.config.HashAlgorithm; algorithm {
+	case config.ALGORITHM_BCRYPT:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), p.config.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	case config.ALGORITHM_ARGON2ID:
+		salt := make([]byte, ARGON2_SALT_LENGTH)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, ARGON2_ITERATIONS, ARGON2_MEMORY, ARGON2_PARALLELISM, ARGON2_KEY_LENGTH)
+		// PHC string format, as produced by the reference implementation.
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+			ARGON2_MEMORY, ARGON2_ITERATIONS, ARGON2_PARALLELISM,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm: %v", algorithm)
+	}
+}
+
+// VerifyPassword compares a password with a hash created by HashPassword.
+// Hashes in an unknown format never match.
+func VerifyPassword(hash string, password string) bool {
+	// The password column is CHAR(68) in older schemas, which pads bcrypt hashes with spaces.
+	hash = strings.TrimRight(hash, " ")
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(candidate, key) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NeedsRehash reports whether the hash was created with another algorithm or weaker parameters
+// than HashPassword currently uses, so it should be replaced after a successful login.
+func (p *Passwords) NeedsRehash(hash string) bool {
+	hash = strings.TrimRight(hash, " ")
+	switch p.config.HashAlgorithm {
+	case config.ALGORITHM_BCRYPT:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost < p.config.BcryptCost
+	case config.ALGORITHM_ARGON2ID:
+		params, _, key, err := parseArgon2Hash(hash)
+		return err != nil || params.memory < ARGON2_MEMORY || params.iterations < ARGON2_ITERATIONS ||
+			params.parallelism < ARGON2_PARALLELISM || len(key) < ARGON2_KEY_LENGTH
+	default:
+		return false
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func parseArgon2Hash(hash string) (*argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != config.ALGORITHM_ARGON2ID {
+		return nil, nil, nil, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2 version: %v", parts[2])
+	}
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_PASSWORD_MIN_LENGTH = 8
+	// bcrypt ignores everything after 72 bytes.
+	DEFAULT_PASSWORD_MAX_LENGTH = 72
+)
+
+// CheckPasswordPolicy validates a new password of the user
+// against the length limits and the breached passwords of the config.
+func (p *Passwords) CheckPasswordPolicy(username string, password string) error {
+	minLength := p.config.MinLength
+	maxLength := p.config.MaxLength
+	if length := utf8.RuneCountInString(password); length < minLength {
+		return fmt.Errorf("password must have at least %v characters", minLength)
+	}
+	if len(password) > maxLength {
+		return fmt.Errorf("password must not be longer than %v bytes", maxLength)
+	}
+	if isSimilarToUsername(username, password) {
+		return fmt.Errorf("password must not contain the username")
+	}
+	if _, breached := p.breachedPasswords[sha1Hex(password)]; breached {
+		return fmt.Errorf("password appears in a list of breached passwords")
+	}
+	return nil
+}
+
+func isSimilarToUsername(username string, password string) bool {
+	username = strings.ToLower(username)
+	password = strings.ToLower(password)
+	if username == "" {
+		return false
+	}
+	return strings.Contains(password, username) || strings.Contains(password, reverse(username)) ||
+		strings.Contains(username, password)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// loadBreachedPasswords reads one password per line. Lines may also be upper case SHA-1 hashes,
+// optionally followed by ":count" as in the Have I Been Pwned downloads.
+// Only hashes are kept in memory.
+func loadBreachedPasswords(path string) (map[string]struct{}, error) {
+	passwords := make(map[string]struct{})
+	if path == "" {
+		return passwords, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) == sha1.Size*2 && strings.ToUpper(hash) == hash {
+			if _, err := hex.DecodeString(hash); err == nil {
+				passwords[hash] = struct{}{}
+				continue
+			}
+		}
+		passwords[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return passwords, nil
+}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,152 @@
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skyrenx/blog-api-go/http/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestPasswords(t *testing.T, algorithm string, bcryptCost int) *Passwords {
+	t.Helper()
+	passwords, err := NewPasswords(config.PasswordConfig{HashAlgorithm: algorithm, BcryptCost: bcryptCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passwords
+}
+
+func hashPassword(t *testing.T, algorithm string, bcryptCost int, password string) string {
+	t.Helper()
+	hash, err := newTestPasswords(t, algorithm, bcryptCost).HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestParseArgon2Hash(t *testing.T) {
+	tests := []struct {
+		name       string
+		hash       string
+		wantParams argon2Params
+		wantErr    bool
+	}{
+		{
+			name:       "owasp parameters",
+			hash:       "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$" + strings.Repeat("A", 43),
+			wantParams: argon2Params{memory: 19456, iterations: 2, parallelism: 1},
+		},
+		{
+			name:       "other parameters",
+			hash:       "$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$a2V5",
+			wantParams: argon2Params{memory: 65536, iterations: 3, parallelism: 4},
+		},
+		{name: "argon2i", hash: "$argon2i$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$a2V5", wantErr: true},
+		{name: "older version", hash: "$argon2id$v=16$m=19456,t=2,p=1$c29tZXNhbHQ$a2V5", wantErr: true},
+		{name: "missing parameter", hash: "$argon2id$v=19$m=19456,t=2$c29tZXNhbHQ$a2V5", wantErr: true},
+		{name: "invalid salt", hash: "$argon2id$v=19$m=19456,t=2,p=1$not base64$a2V5", wantErr: true},
+		{name: "empty key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$", wantErr: true},
+		{name: "missing key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ", wantErr: true},
+		{name: "bcrypt", hash: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _, _, err := parseArgon2Hash(tt.hash)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseArgon2Hash succeeded with %+v", params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseArgon2Hash failed: %v", err)
+			}
+			if *params != tt.wantParams {
+				t.Errorf("params = %+v, want %+v", *params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash := hashPassword(t, config.ALGORITHM_BCRYPT, bcrypt.MinCost, "correct horse")
+	argon2Hash := hashPassword(t, config.ALGORITHM_ARGON2ID, 0, "correct horse")
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{name: "bcrypt", hash: bcryptHash, password: "correct horse", want: true},
+		{name: "bcrypt padded by CHAR(68)", hash: bcryptHash + strings.Repeat(" ", 68-len(bcryptHash)), password: "correct horse", want: true},
+		{name: "bcrypt with wrong password", hash: bcryptHash, password: "battery staple"},
+		{name: "argon2id", hash: argon2Hash, password: "correct horse", want: true},
+		{name: "argon2id with wrong password", hash: argon2Hash, password: "battery staple"},
+		{name: "argon2id with broken parameters", hash: strings.Replace(argon2Hash, "t=2", "t=x", 1), password: "correct horse"},
+		{name: "unknown format", hash: "plain:correct horse", password: "correct horse"},
+		{name: "empty hash", hash: "", password: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPassword(tt.hash, tt.password); got != tt.want {
+				t.Errorf("VerifyPassword = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPasswordFormat(t *testing.T) {
+	tests := []struct {
+		algorithm  string
+		wantPrefix string
+	}{
+		{algorithm: config.ALGORITHM_BCRYPT, wantPrefix: "$2a$04$"},
+		{algorithm: config.ALGORITHM_ARGON2ID, wantPrefix: "$argon2id$v=19$m=19456,t=2,p=1$"},
+		{algorithm: "", wantPrefix: "$2a$04$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hash := hashPassword(t, tt.algorithm, bcrypt.MinCost, "correct horse")
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Errorf("hash = %v, want prefix %v", hash, tt.wantPrefix)
+			}
+			if other := hashPassword(t, tt.algorithm, bcrypt.MinCost, "correct horse"); other == hash {
+				t.Error("hashes of the same password are equal, the salt is not random")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptMinCost := hashPassword(t, config.ALGORITHM_BCRYPT, bcrypt.MinCost, "correct horse")
+	bcryptCost5 := hashPassword(t, config.ALGORITHM_BCRYPT, bcrypt.MinCost+1, "correct horse")
+	argon2Hash := hashPassword(t, config.ALGORITHM_ARGON2ID, 0, "correct horse")
+	weakArgon2Hash := strings.Replace(argon2Hash, "m=19456", "m=4096", 1)
+	tests := []struct {
+		name       string
+		algorithm  string
+		bcryptCost int
+		hash       string
+		want       bool
+	}{
+		{name: "bcrypt with the configured cost", algorithm: config.ALGORITHM_BCRYPT, bcryptCost: bcrypt.MinCost, hash: bcryptMinCost},
+		{name: "bcrypt with a higher cost", algorithm: config.ALGORITHM_BCRYPT, bcryptCost: bcrypt.MinCost, hash: bcryptCost5},
+		{name: "bcrypt with a lower cost", algorithm: config.ALGORITHM_BCRYPT, bcryptCost: bcrypt.MinCost + 1, hash: bcryptMinCost, want: true},
+		{name: "bcrypt padded by CHAR(68)", algorithm: config.ALGORITHM_BCRYPT, bcryptCost: bcrypt.MinCost, hash: bcryptMinCost + "         "},
+		{name: "argon2id while bcrypt is configured", algorithm: config.ALGORITHM_BCRYPT, bcryptCost: bcrypt.MinCost, hash: argon2Hash, want: true},
+		{name: "bcrypt while argon2id is configured", algorithm: config.ALGORITHM_ARGON2ID, hash: bcryptMinCost, want: true},
+		{name: "argon2id with the current parameters", algorithm: config.ALGORITHM_ARGON2ID, hash: argon2Hash},
+		{name: "argon2id with less memory", algorithm: config.ALGORITHM_ARGON2ID, hash: weakArgon2Hash, want: true},
+		{name: "malformed argon2id", algorithm: config.ALGORITHM_ARGON2ID, hash: "$argon2id$v=19$", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passwords := newTestPasswords(t, tt.algorithm, tt.bcryptCost)
+			if got := passwords.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+)
+
+type AccountService struct {
+	users       repository.UserRepository
+	blogEntries repository.BlogEntryRepository
+	apiKeys     repository.ApiKeyRepository
+	auditLog    repository.AuditLogRepository
+}
+
+func NewAccountService(users repository.UserRepository, blogEntries repository.BlogEntryRepository,
+	apiKeys repository.ApiKeyRepository, auditLog repository.AuditLogRepository) *AccountService {
+	return &AccountService{users: users, blogEntries: blogEntries, apiKeys: apiKeys, auditLog: auditLog}
+}
+
+// ExportAccount collects the personal data of the user.
+func (s *AccountService) ExportAccount(ctx context.Context, username string) (*dto.UserExport, error) {
+	ctx, span := tracer.Start(ctx, "AccountService.ExportAccount")
+	defer span.End()
+	export, err := s.exportAccount(ctx, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in ExportAccount", "error", err)
+		return nil, fmt.Errorf("could not export the user: %v: %w", username, err)
+	}
+	// The export itself succeeded, failing to audit it only gets logged.
+	err = s.auditLog.CreateAuditLogEntry(ctx, newAuditLogEntry(username, entities.AUDIT_ACTION_USER_EXPORT, username, ""))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in ExportAccount", "error", err)
+	}
+	return export, nil
+}
+
+func (s *AccountService) exportAccount(ctx context.Context, username string) (*dto.UserExport, error) {
+	account, err := s.users.GetUserAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, apperror.NotFound("user does not exist: %v", username)
+	}
+	blogEntries, err := s.blogEntries.GetBlogEntriesByAuthor(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := s.apiKeys.GetApiKeysByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := s.users.GetUserIdentitiesByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.users.GetSessionsByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UserExport{
+		ExportedAt:  time.Now(),
+		Profile:     *account,
+		BlogEntries: blogEntries,
+		Comments:    []any{},
+		Sessions:    sessions,
+		ApiKeys:     apiKeys,
+		Identities:  identities,
+	}, nil
+}
+
+func (s *AccountService) ValidateAccountDeletionRequest(username string, request dto.AccountDeletionRequest) error {
+	switch request.Mode {
+	case dto.ACCOUNT_DELETION_MODE_DELETE, dto.ACCOUNT_DELETION_MODE_ANONYMIZE:
+	default:
+		return apperror.Validation("mode must be %q or %q", dto.ACCOUNT_DELETION_MODE_DELETE, dto.ACCOUNT_DELETION_MODE_ANONYMIZE)
+	}
+	switch request.Entries {
+	case dto.ENTRIES_POLICY_DELETE, dto.ENTRIES_POLICY_ANONYMIZE:
+	case dto.ENTRIES_POLICY_REASSIGN:
+		if request.ReassignTo == "" || request.ReassignTo == username {
+			return apperror.Validation("reassign_to must name another user")
+		}
+	default:
+		return apperror.Validation("entries must be %q, %q or %q",
+			dto.ENTRIES_POLICY_DELETE, dto.ENTRIES_POLICY_REASSIGN, dto.ENTRIES_POLICY_ANONYMIZE)
+	}
+	return nil
+}
+
+// DeleteAccount deletes or anonymizes the account of the user and records it in the audit log.
+func (s *AccountService) DeleteAccount(ctx context.Context, username string, request dto.AccountDeletionRequest) error {
+	ctx, span := tracer.Start(ctx, "AccountService.DeleteAccount")
+	defer span.End()
+	if err := s.ValidateAccountDeletionRequest(username, request); err != nil {
+		return err
+	}
+	suffix, err := randomString(6, hex.EncodeToString)
+	if err != nil {
+		return err
+	}
+	alias := "deleted-" + suffix
+
+	action := entities.AUDIT_ACTION_USER_DELETE
+	if request.Mode == dto.ACCOUNT_DELETION_MODE_ANONYMIZE {
+		action = entities.AUDIT_ACTION_USER_ANONYMIZE
+	}
+	// The audit log must not keep the username of an erased user, only the alias.
+	details := fmt.Sprintf("entries=%v", request.Entries)
+	if request.Entries == dto.ENTRIES_POLICY_REASSIGN {
+		details += " reassign_to=" + request.ReassignTo
+	}
+	audit := newAuditLogEntry(alias, action, alias, details)
+
+	if err := s.users.DeleteAccount(ctx, username, request, alias, audit); err != nil {
+		slog.ErrorContext(ctx, "Error in DeleteAccount", "error", err)
+		return fmt.Errorf("could not delete the user: %v: %w", username, err)
+	}
+	return nil
+}
+
+func newAuditLogEntry(actor string, action string, target string, details string) entities.AuditLogEntry {
+	id, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return entities.AuditLogEntry{
+		ID:        id,
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+)
+
+const MAX_AUTHORITY_LENGTH = 50
+
+// Administrators cannot lock themselves out.
+var ErrSelfAdministration = apperror.Forbidden("administrators cannot disable, delete or demote themselves")
+
+type AdminService struct {
+	users repository.UserRepository
+}
+
+func NewAdminService(users repository.UserRepository) *AdminService {
+	return &AdminService{users: users}
+}
+
+// SearchUsers returns a page of users whose username contains search and the number of pages.
+func (s *AdminService) SearchUsers(ctx context.Context, search string, pageNumber int, pageSize int) ([]dto.UserAccount, int, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.SearchUsers")
+	defer span.End()
+	if pageNumber < 1 {
+		return nil, 0, apperror.Validation("requested page number should be greater than 0")
+	}
+	if pageSize < 1 {
+		return nil, 0, apperror.Validation("requested page size should be greater than 0")
+	}
+	if pageSize > MAX_PAGE_SIZE {
+		return nil, 0, apperror.Validation("requested page size should be at most %v", MAX_PAGE_SIZE)
+	}
+	accounts, totalRows, err := s.users.SearchUserAccounts(ctx, search, pageNumber, pageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in SearchUsers", "error", err)
+		return nil, 0, fmt.Errorf("could not search users: %v", search)
+	}
+	totalPages := (totalRows + pageSize - 1) / pageSize
+	return accounts, totalPages, nil
+}
+
+// Returns nil if the user does not exist.
+func (s *AdminService) GetUserAccount(ctx context.Context, username string) (*dto.UserAccount, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetUserAccount")
+	defer span.End()
+	account, err := s.users.GetUserAccount(ctx, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in GetUserAccount", "error", err)
+		return nil, fmt.Errorf("could not get the account of the user: %v", username)
+	}
+	return account, nil
+}
+
+// Returns false if the user does not exist.
+func (s *AdminService) SetUserEnabled(ctx context.Context, admin string, username string, enabled bool) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.SetUserEnabled")
+	defer span.End()
+	if admin == username && !enabled {
+		return false, ErrSelfAdministration
+	}
+	action := entities.AUDIT_ACTION_USER_DISABLE
+	if enabled {
+		action = entities.AUDIT_ACTION_USER_ENABLE
+	}
+	audit := newAuditLogEntry(admin, action, username, "")
+	found, err := s.users.SetUserEnabled(ctx, username, enabled, audit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in SetUserEnabled", "error", err)
+		return false, fmt.Errorf("could not update the user: %v", username)
+	}
+	return found, nil
+}
+
+// Returns false if the user does not exist.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, admin string, username string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.ForcePasswordReset")
+	defer span.End()
+	audit := newAuditLogEntry(admin, entities.AUDIT_ACTION_USER_PASSWORD_RESET, username, "")
+	found, err := s.users.RequirePasswordReset(ctx, username, audit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in ForcePasswordReset", "error", err)
+		return false, fmt.Errorf("could not update the user: %v", username)
+	}
+	return found, nil
+}
+
+func (s *AdminService) ValidateAuthority(authority string) error {
+	if authority == "" || len(authority) > MAX_AUTHORITY_LENGTH {
+		return apperror.Validation("authority must have 1 to %v characters", MAX_AUTHORITY_LENGTH)
+	}
+	return nil
+}
+
+// Returns false if the user does not exist.
+func (s *AdminService) GrantAuthority(ctx context.Context, admin string, username string, authority string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GrantAuthority")
+	defer span.End()
+	if err := s.ValidateAuthority(authority); err != nil {
+		return false, err
+	}
+	account, err := s.GetUserAccount(ctx, username)
+	if err != nil || account == nil {
+		return false, err
+	}
+	audit := newAuditLogEntry(admin, entities.AUDIT_ACTION_AUTHORITY_GRANT, username, "authority="+authority)
+	err = s.users.AddAuthority(ctx, entities.Authority{Username: username, Authority: authority}, audit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in GrantAuthority", "error", err)
+		return false, fmt.Errorf("could not grant %v to the user: %v", authority, username)
+	}
+	return true, nil
+}
+
+// Returns false if the user does not exist.
+func (s *AdminService) RevokeAuthority(ctx context.Context, admin string, username string, authority string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.RevokeAuthority")
+	defer span.End()
+	if admin == username && authority == entities.AUTHORITY_ADMIN {
+		return false, ErrSelfAdministration
+	}
+	account, err := s.GetUserAccount(ctx, username)
+	if err != nil || account == nil {
+		return false, err
+	}
+	audit := newAuditLogEntry(admin, entities.AUDIT_ACTION_AUTHORITY_REVOKE, username, "authority="+authority)
+	err = s.users.RemoveAuthority(ctx, entities.Authority{Username: username, Authority: authority}, audit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in RevokeAuthority", "error", err)
+		return false, fmt.Errorf("could not revoke %v from the user: %v", authority, username)
+	}
+	return true, nil
+}
+
+// Returns false if the user does not exist.
+func (s *AdminService) DeleteUser(ctx context.Context, admin string, username string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.DeleteUser")
+	defer span.End()
+	if admin == username {
+		return false, ErrSelfAdministration
+	}
+	audit := newAuditLogEntry(admin, entities.AUDIT_ACTION_USER_DELETE, username, "")
+	found, err := s.users.DeleteUser(ctx, username, audit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in DeleteUser", "error", err)
+		return false, fmt.Errorf("could not delete the user: %v", username)
+	}
+	return found, nil
+}
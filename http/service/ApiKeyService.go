@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+)
+
+const (
+	API_KEY_PREFIX = "bk_"
+	// last_used_at is only written once per interval to avoid a write on every request.
+	API_KEY_LAST_USED_INTERVAL = time.Minute
+)
+
+type ApiKeyService struct {
+	apiKeys repository.ApiKeyRepository
+}
+
+func NewApiKeyService(apiKeys repository.ApiKeyRepository) *ApiKeyService {
+	return &ApiKeyService{apiKeys: apiKeys}
+}
+
+func (s *ApiKeyService) ValidateApiKeyRequest(request dto.ApiKeyRequest) error {
+	if request.Name == "" {
+		return apperror.Validation("api key name cannot be empty")
+	}
+	if len(request.Scopes) == 0 {
+		return apperror.Validation("api key needs at least one scope")
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(entities.ApiKeyScopes, scope) {
+			return apperror.Validation("unknown scope: %v", scope)
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return apperror.Validation("api key expiration must be in the future")
+	}
+	return nil
+}
+
+func (s *ApiKeyService) CreateApiKey(ctx context.Context, username string, request dto.ApiKeyRequest) (*dto.ApiKeyWithSecret, error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.CreateApiKey")
+	defer span.End()
+	if err := s.ValidateApiKeyRequest(request); err != nil {
+		return nil, err
+	}
+
+	id, err := randomString(16, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	key := API_KEY_PREFIX + secret
+
+	apiKey := entities.ApiKey{
+		ID:        id,
+		Username:  username,
+		Name:      request.Name,
+		Prefix:    key[:len(API_KEY_PREFIX)+6],
+		KeyHash:   hashApiKey(key),
+		Scopes:    strings.Join(request.Scopes, ","),
+		CreatedAt: time.Now(),
+		ExpiresAt: request.ExpiresAt,
+	}
+	if err := s.apiKeys.CreateApiKey(ctx, apiKey); err != nil {
+		slog.ErrorContext(ctx, "Error in CreateApiKey", "error", err)
+		return nil, fmt.Errorf("could not create api key for user: %v", username)
+	}
+	return &dto.ApiKeyWithSecret{ApiKey: apiKey, Key: key}, nil
+}
+
+func (s *ApiKeyService) GetApiKeys(ctx context.Context, username string) ([]entities.ApiKey, error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.GetApiKeys")
+	defer span.End()
+	apiKeys, err := s.apiKeys.GetApiKeysByUsername(ctx, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in GetApiKeys", "error", err)
+		return nil, fmt.Errorf("could not get api keys of user: %v", username)
+	}
+	return apiKeys, nil
+}
+
+// Returns false if the user has no api key with the given id.
+func (s *ApiKeyService) RevokeApiKey(ctx context.Context, username string, id string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.RevokeApiKey")
+	defer span.End()
+	found, err := s.apiKeys.DeleteApiKey(ctx, username, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in RevokeApiKey", "error", err)
+		return false, fmt.Errorf("could not revoke api key: %v", id)
+	}
+	return found, nil
+}
+
+// AuthenticateApiKey resolves a plain api key to the principal it was issued for.
+func (s *ApiKeyService) AuthenticateApiKey(ctx context.Context, key string) (*entities.Principal, error) {
+	ctx, span := tracer.Start(ctx, "ApiKeyService.AuthenticateApiKey")
+	defer span.End()
+	if !strings.HasPrefix(key, API_KEY_PREFIX) {
+		return nil, apperror.Unauthorized("malformed api key")
+	}
+	apiKey, err := s.apiKeys.GetApiKeyByHash(ctx, hashApiKey(key))
+	if apperror.KindOf(err) == apperror.NOT_FOUND {
+		return nil, apperror.Unauthorized("invalid api key")
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in AuthenticateApiKey", "error", err)
+		return nil, fmt.Errorf("could not get the api key")
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return nil, apperror.Unauthorized("api key has expired: %v", apiKey.ID)
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > API_KEY_LAST_USED_INTERVAL {
+		// Failing to track usage should not fail the request.
+		if err := s.apiKeys.UpdateApiKeyLastUsed(ctx, apiKey.ID, now); err != nil {
+			slog.ErrorContext(ctx, "Error in AuthenticateApiKey", "error", err)
+		}
+	}
+	return &entities.Principal{
+		Username:   apiKey.Username,
+		AuthMethod: entities.AUTH_METHOD_API_KEY,
+		ApiKeyID:   apiKey.ID,
+		Scopes:     strings.Split(apiKey.Scopes, ","),
+	}, nil
+}
+
+// Api keys are long random strings, so a fast hash is enough and allows looking them up by hash.
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
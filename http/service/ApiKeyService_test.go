@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+	"github.com/skyrenx/blog-api-go/http/security"
+)
+
+func TestHashApiKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "", want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{key: "bk_secret", want: "2be0fe97f64caabd001f7f9b3a366371ba2927714e9177af6fd57a479067be46"},
+		{key: "bk_Secret", want: "2448a1b7b7970cfe5531b393c0d627216e07a94b950e702a92ffcd747888880c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := hashApiKey(tt.key); got != tt.want {
+				t.Errorf("hashApiKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateApiKey(t *testing.T) {
+	apiKeys := repository.NewMemory()
+	service := NewApiKeyService(apiKeys)
+	created, err := service.CreateApiKey(context.Background(), "alice",
+		dto.ApiKeyRequest{Name: "ci", Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, API_KEY_PREFIX) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("key %v does not start with %v and its prefix %v", created.Key, API_KEY_PREFIX, created.Prefix)
+	}
+	stored, err := apiKeys.GetApiKeyByHash(context.Background(), hashApiKey(created.Key))
+	if err != nil {
+		t.Fatalf("api key is not stored by its hash: %v", err)
+	}
+	if stored.Username != "alice" || stored.Scopes != entities.SCOPE_BLOG_ENTRIES_WRITE || stored.ID != created.ID {
+		t.Errorf("stored api key = %+v, want the created one of alice", stored)
+	}
+}
+
+func TestValidateApiKeyRequest(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		request dto.ApiKeyRequest
+		wantErr bool
+	}{
+		{name: "valid", request: dto.ApiKeyRequest{Name: "ci", Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}}},
+		{name: "expires in the future", request: dto.ApiKeyRequest{Name: "ci", Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}, ExpiresAt: &future}},
+		{name: "missing name", request: dto.ApiKeyRequest{Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}}, wantErr: true},
+		{name: "missing scopes", request: dto.ApiKeyRequest{Name: "ci"}, wantErr: true},
+		{name: "unknown scope", request: dto.ApiKeyRequest{Name: "ci", Scopes: []string{"admin"}}, wantErr: true},
+		{name: "expired", request: dto.ApiKeyRequest{Name: "ci", Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}, ExpiresAt: &past}, wantErr: true},
+	}
+	service := NewApiKeyService(repository.NewMemory())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateApiKeyRequest(tt.request)
+			if tt.wantErr && apperror.KindOf(err) != apperror.VALIDATION {
+				t.Errorf("error = %v, want kind %v", err, apperror.VALIDATION)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("error = %v", err)
+			}
+		})
+	}
+}
+
+type authTestEnv struct {
+	users   *repository.Memory
+	tokens  *security.Tokens
+	service *AuthService
+	// Plain key of alice, valid for SCOPE_BLOG_ENTRIES_WRITE.
+	apiKey string
+}
+
+func newAuthTestEnv(t *testing.T) *authTestEnv {
+	t.Helper()
+	ctx := context.Background()
+	users := repository.NewMemory()
+	tokens := security.NewTokens(config.TokenConfig{}, secrets.Static("test-secret"))
+	apiKeyService := NewApiKeyService(users)
+	for _, username := range []string{"alice", "disabled", "reset"} {
+		if err := users.RegisterUser(ctx, entities.User{Username: username, Password: "$2a$04$hash"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users.SetUserEnabled(ctx, "disabled", false, entities.AuditLogEntry{})
+	users.RequirePasswordReset(ctx, "reset", entities.AuditLogEntry{})
+	users.AddAuthority(ctx, entities.Authority{Username: "alice", Authority: "ROLE_ADMIN"}, entities.AuditLogEntry{})
+
+	created, err := apiKeyService.CreateApiKey(ctx, "alice",
+		dto.ApiKeyRequest{Name: "ci", Scopes: []string{entities.SCOPE_BLOG_ENTRIES_WRITE}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authTestEnv{users: users, tokens: tokens, service: NewAuthService(users, apiKeyService, tokens), apiKey: created.Key}
+}
+
+func (e *authTestEnv) bearer(t *testing.T, username string) string {
+	t.Helper()
+	token, _, err := e.tokens.GenerateJWT(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// changePassword records a password change of alice at changedAt.
+func (e *authTestEnv) changePassword(t *testing.T, changedAt time.Time) {
+	t.Helper()
+	if _, err := e.users.UpdatePassword(context.Background(), "alice", "$2a$04$new", changedAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expiredApiKey stores a key of alice that expired an hour ago.
+func (e *authTestEnv) expiredApiKey(t *testing.T) string {
+	t.Helper()
+	key := API_KEY_PREFIX + "expired"
+	expiresAt := time.Now().Add(-time.Hour)
+	err := e.users.CreateApiKey(context.Background(), entities.ApiKey{ID: "expired", Username: "alice", KeyHash: hashApiKey(key),
+		Scopes: entities.SCOPE_BLOG_ENTRIES_WRITE, ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization func(t *testing.T, e *authTestEnv) string
+		wantUsername  string
+		wantMethod    string
+		wantScopes    []string
+		wantErr       bool
+	}{
+		{
+			name:          "jwt",
+			authorization: func(t *testing.T, e *authTestEnv) string { return e.bearer(t, "alice") },
+			wantUsername:  "alice",
+			wantMethod:    entities.AUTH_METHOD_JWT,
+		},
+		{
+			name:          "api key",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "ApiKey " + e.apiKey },
+			wantUsername:  "alice",
+			wantMethod:    entities.AUTH_METHOD_API_KEY,
+			wantScopes:    []string{entities.SCOPE_BLOG_ENTRIES_WRITE},
+		},
+		{
+			name:          "scheme is case insensitive",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "apikey " + e.apiKey },
+			wantUsername:  "alice",
+			wantMethod:    entities.AUTH_METHOD_API_KEY,
+			wantScopes:    []string{entities.SCOPE_BLOG_ENTRIES_WRITE},
+		},
+		{
+			name:          "unknown api key",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "ApiKey " + API_KEY_PREFIX + "unknown" },
+			wantErr:       true,
+		},
+		{
+			name: "api key without prefix",
+			authorization: func(t *testing.T, e *authTestEnv) string {
+				return "ApiKey " + strings.TrimPrefix(e.apiKey, API_KEY_PREFIX)
+			},
+			wantErr: true,
+		},
+		{
+			name:          "expired api key",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "ApiKey " + e.expiredApiKey(t) },
+			wantErr:       true,
+		},
+		{
+			name:          "api key as bearer token",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "Bearer " + e.apiKey },
+			wantErr:       true,
+		},
+		{
+			name:          "jwt of a disabled user",
+			authorization: func(t *testing.T, e *authTestEnv) string { return e.bearer(t, "disabled") },
+			wantErr:       true,
+		},
+		{
+			name:          "jwt of a user that has to reset the password",
+			authorization: func(t *testing.T, e *authTestEnv) string { return e.bearer(t, "reset") },
+			wantErr:       true,
+		},
+		{
+			name:          "jwt of a deleted user",
+			authorization: func(t *testing.T, e *authTestEnv) string { return e.bearer(t, "deleted") },
+			wantErr:       true,
+		},
+		{
+			name: "jwt issued before the password was changed",
+			authorization: func(t *testing.T, e *authTestEnv) string {
+				authorization := e.bearer(t, "alice")
+				e.changePassword(t, time.Now().Add(time.Minute))
+				return authorization
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwt issued after the password was changed",
+			authorization: func(t *testing.T, e *authTestEnv) string {
+				e.changePassword(t, time.Now().Add(-time.Minute))
+				return e.bearer(t, "alice")
+			},
+			wantUsername: "alice",
+			wantMethod:   entities.AUTH_METHOD_JWT,
+		},
+		{
+			name: "jwt issued in the second of the password change",
+			authorization: func(t *testing.T, e *authTestEnv) string {
+				e.changePassword(t, time.Now())
+				return e.bearer(t, "alice")
+			},
+			wantUsername: "alice",
+			wantMethod:   entities.AUTH_METHOD_JWT,
+		},
+		{
+			name: "api key survives a password change",
+			authorization: func(t *testing.T, e *authTestEnv) string {
+				e.changePassword(t, time.Now().Add(time.Minute))
+				return "ApiKey " + e.apiKey
+			},
+			wantUsername: "alice",
+			wantMethod:   entities.AUTH_METHOD_API_KEY,
+			wantScopes:   []string{entities.SCOPE_BLOG_ENTRIES_WRITE},
+		},
+		{
+			name:          "unsupported scheme",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "Basic YWxpY2U6cGFzc3dvcmQ=" },
+			wantErr:       true,
+		},
+		{
+			name:          "missing credentials",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "Bearer " },
+			wantErr:       true,
+		},
+		{
+			name:          "empty header",
+			authorization: func(t *testing.T, e *authTestEnv) string { return "" },
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newAuthTestEnv(t)
+			principal, err := e.service.Authenticate(context.Background(), tt.authorization(t, e))
+			if tt.wantErr {
+				if apperror.KindOf(err) != apperror.UNAUTHORIZED {
+					t.Fatalf("error = %v, want kind %v", err, apperror.UNAUTHORIZED)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate failed: %v", err)
+			}
+			if principal.Username != tt.wantUsername || principal.AuthMethod != tt.wantMethod ||
+				strings.Join(principal.Scopes, ",") != strings.Join(tt.wantScopes, ",") {
+				t.Errorf("principal = %+v, want %v with %v and scopes %v", principal, tt.wantUsername, tt.wantMethod, tt.wantScopes)
+			}
+			if strings.Join(principal.Authorities, ",") != "ROLE_ADMIN" {
+				t.Errorf("authorities = %v, want the ones of the account", principal.Authorities)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTracksApiKeyUsage(t *testing.T) {
+	e := newAuthTestEnv(t)
+	principal, err := e.service.Authenticate(context.Background(), "ApiKey "+e.apiKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := e.users.GetApiKeyByHash(context.Background(), hashApiKey(e.apiKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey.ID != principal.ApiKeyID || apiKey.LastUsedAt == nil {
+		t.Errorf("api key = %+v, want it used by principal %+v", apiKey, principal)
+	}
+}
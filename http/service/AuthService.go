@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/security"
+)
+
+type AuthService struct {
+	users   repository.UserRepository
+	apiKeys *ApiKeyService
+	tokens  *security.Tokens
+}
+
+func NewAuthService(users repository.UserRepository, apiKeys *ApiKeyService, tokens *security.Tokens) *AuthService {
+	return &AuthService{users: users, apiKeys: apiKeys, tokens: tokens}
+}
+
+// Authenticate resolves the value of an Authorization header.
+// Both "Bearer <jwt>" and "ApiKey <key>" are accepted.
+// The account is checked on every request, so disabling a user takes effect immediately.
+// Tokens issued before the last password change are rejected.
+func (s *AuthService) Authenticate(ctx context.Context, authorization string) (*entities.Principal, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+	principal, issuedAt, err := s.authenticateCredentials(ctx, authorization)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.users.GetUserAccount(ctx, principal.Username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in Authenticate", "error", err)
+		return nil, fmt.Errorf("could not get the account of the user: %v", principal.Username)
+	}
+	if err := checkAccountActive(account, principal.Username); err != nil {
+		return nil, err
+	}
+	if principal.AuthMethod == entities.AUTH_METHOD_JWT && issuedBeforePasswordChange(issuedAt, account) {
+		return nil, apperror.Unauthorized("token was issued before the password was changed: %v", principal.Username)
+	}
+	principal.Authorities = account.Authorities
+	return principal, nil
+}
+
+// issuedBeforePasswordChange reports whether a token issued at issuedAt was revoked by a password change.
+// Tokens without an issue time predate the tracking of password changes.
+func issuedBeforePasswordChange(issuedAt *time.Time, account *dto.UserAccount) bool {
+	if account.PasswordChangedAt == nil {
+		return false
+	}
+	// Issue times are whole seconds, a token issued in the second of the change is still accepted.
+	return issuedAt == nil || issuedAt.Before(account.PasswordChangedAt.Truncate(time.Second))
+}
+
+// checkAccountActive rejects users that were deleted, disabled or have to reset their password.
+func checkAccountActive(account *dto.UserAccount, username string) error {
+	if account == nil {
+		return apperror.Unauthorized("user does not exist: %v", username)
+	}
+	if !account.Enabled {
+		return apperror.Unauthorized("user is disabled: %v", username)
+	}
+	if account.PasswordResetRequired {
+		return apperror.Unauthorized("user has to reset the password: %v", username)
+	}
+	return nil
+}
+
+// authenticateCredentials also returns the issue time of a token, nil for api keys and tokens without one.
+func (s *AuthService) authenticateCredentials(ctx context.Context, authorization string) (*entities.Principal, *time.Time, error) {
+	scheme, credentials, found := strings.Cut(authorization, " ")
+	if !found || credentials == "" {
+		return nil, nil, apperror.Unauthorized("missing or malformed authorization header")
+	}
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		claims, err := s.tokens.ParseJWT(credentials)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error in Authenticate", "error", err)
+			return nil, nil, apperror.Unauthorized("invalid token")
+		}
+		var issuedAt *time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = &claims.IssuedAt.Time
+		}
+		return &entities.Principal{Username: claims.Username, AuthMethod: entities.AUTH_METHOD_JWT}, issuedAt, nil
+	case "apikey":
+		principal, err := s.apiKeys.AuthenticateApiKey(ctx, credentials)
+		return principal, nil, err
+	default:
+		return nil, nil, apperror.Unauthorized("unsupported authorization scheme: %v", scheme)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+)
+
+func TestPageSize(t *testing.T) {
+	ctx := context.Background()
+	memory := repository.NewMemory()
+	blogEntries := NewBlogEntryService(memory, entities.ID_STRATEGY_SEQUENCE)
+	for i := range 3 {
+		request := dto.BlogEntryRequest{Title: fmt.Sprintf("Entry %v", i), Content: "Content"}
+		if _, err := blogEntries.CreateBlogEntry(ctx, "alice", request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := NewAdminService(memory)
+
+	// Every paged list accepts page sizes from 1 to MAX_PAGE_SIZE.
+	lists := map[string]func(pageSize int) error{
+		"blog entries": func(pageSize int) error {
+			_, _, err := blogEntries.GetBlogEntries(ctx, 1, pageSize)
+			return err
+		},
+		"blog entry summaries": func(pageSize int) error {
+			_, _, err := blogEntries.GetBlogEntrySummaries(ctx, 1, pageSize)
+			return err
+		},
+		"users": func(pageSize int) error {
+			_, _, err := admin.SearchUsers(ctx, "", 1, pageSize)
+			return err
+		},
+	}
+	tests := []struct {
+		pageSize  int
+		wantValid bool
+	}{
+		{pageSize: 0, wantValid: false},
+		{pageSize: 1, wantValid: true},
+		{pageSize: MAX_PAGE_SIZE, wantValid: true},
+		{pageSize: MAX_PAGE_SIZE + 1, wantValid: false},
+		{pageSize: 1 << 40, wantValid: false},
+	}
+	for name, list := range lists {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%v %v", name, tt.pageSize), func(t *testing.T) {
+				err := list(tt.pageSize)
+				if tt.wantValid && err != nil {
+					t.Errorf("error = %v, want nil", err)
+				}
+				if !tt.wantValid && apperror.KindOf(err) != apperror.VALIDATION {
+					t.Errorf("error = %v, want kind %v", err, apperror.VALIDATION)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+)
+
+// Time a single readiness check may take before it counts as failed.
+const READINESS_CHECK_TIMEOUT = 2 * time.Second
+
+// HealthCheck is a dependency the api needs to serve requests.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthService struct {
+	checks []HealthCheck
+	// Time a single check may take, READINESS_CHECK_TIMEOUT unless a test shortens it.
+	timeout time.Duration
+}
+
+func NewHealthService(checks ...HealthCheck) *HealthService {
+	return &HealthService{checks: checks, timeout: READINESS_CHECK_TIMEOUT}
+}
+
+// DatabaseCheck checks that the database can be reached.
+func DatabaseCheck(database repository.Pinger) HealthCheck {
+	return HealthCheck{Name: "database", Check: database.Ping}
+}
+
+// MigrationsCheck checks that all migrations of this build are applied.
+func MigrationsCheck(migrator *repository.Migrator) HealthCheck {
+	return HealthCheck{Name: "migrations", Check: migrator.CheckCurrent}
+}
+
+// SigningKeyCheck checks that the key tokens are signed with is loaded.
+func SigningKeyCheck(secret *secrets.Secret) HealthCheck {
+	return HealthCheck{Name: "signing_key", Check: func(ctx context.Context) error {
+		if secret.Value() == "" {
+			return errors.New("JWT_SECRET is empty")
+		}
+		return nil
+	}}
+}
+
+// Readiness runs all checks concurrently, each bounded by the timeout of the service.
+// The details of failures are only logged, the result names the failed dependencies.
+func (s *HealthService) Readiness(ctx context.Context) dto.Health {
+	health := dto.Health{Status: dto.HEALTH_STATUS_OK, Checks: make([]dto.CheckResult, len(s.checks))}
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health.Checks[i] = runCheck(ctx, check, s.timeout)
+		}()
+	}
+	wg.Wait()
+	for _, result := range health.Checks {
+		if result.Status != dto.HEALTH_STATUS_OK {
+			health.Status = dto.HEALTH_STATUS_FAIL
+		}
+	}
+	return health
+}
+
+func runCheck(ctx context.Context, check HealthCheck, timeout time.Duration) dto.CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Check(ctx)
+	result := dto.CheckResult{
+		Name:      check.Name,
+		Status:    dto.HEALTH_STATUS_OK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err == nil {
+		return result
+	}
+	slog.WarnContext(ctx, "Readiness check failed", "check", check.Name, "error", err)
+	result.Status = dto.HEALTH_STATUS_FAIL
+	result.Error = "unavailable"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.Error = fmt.Sprintf("timed out after %v", timeout)
+	}
+	return result
+}
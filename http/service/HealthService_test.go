@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+)
+
+func TestReadiness(t *testing.T) {
+	ok := HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}}
+	// hanging only returns when its context is done, as a database that does not answer.
+	hanging := HealthCheck{Name: "database", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantStatus string
+		wantChecks []dto.CheckResult
+	}{
+		{
+			name:       "no checks",
+			wantStatus: dto.HEALTH_STATUS_OK,
+			wantChecks: []dto.CheckResult{},
+		},
+		{
+			name:       "all checks pass",
+			checks:     []HealthCheck{ok, SigningKeyCheck(secrets.Static("secret"))},
+			wantStatus: dto.HEALTH_STATUS_OK,
+			wantChecks: []dto.CheckResult{
+				{Name: "database", Status: dto.HEALTH_STATUS_OK},
+				{Name: "signing_key", Status: dto.HEALTH_STATUS_OK},
+			},
+		},
+		{
+			name:       "one failing check fails readiness",
+			checks:     []HealthCheck{ok, failing},
+			wantStatus: dto.HEALTH_STATUS_FAIL,
+			wantChecks: []dto.CheckResult{
+				{Name: "database", Status: dto.HEALTH_STATUS_OK},
+				{Name: "migrations", Status: dto.HEALTH_STATUS_FAIL, Error: "unavailable"},
+			},
+		},
+		{
+			name:       "empty signing key",
+			checks:     []HealthCheck{SigningKeyCheck(secrets.Static(""))},
+			wantStatus: dto.HEALTH_STATUS_FAIL,
+			wantChecks: []dto.CheckResult{{Name: "signing_key", Status: dto.HEALTH_STATUS_FAIL, Error: "unavailable"}},
+		},
+		{
+			name:       "timeout counts as failure",
+			checks:     []HealthCheck{hanging, SigningKeyCheck(secrets.Static("secret"))},
+			wantStatus: dto.HEALTH_STATUS_FAIL,
+			wantChecks: []dto.CheckResult{
+				{Name: "database", Status: dto.HEALTH_STATUS_FAIL, Error: "timed out after 10ms"},
+				{Name: "signing_key", Status: dto.HEALTH_STATUS_OK},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealthService(tt.checks...)
+			health.timeout = 10 * time.Millisecond
+			got := health.Readiness(context.Background())
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if len(got.Checks) != len(tt.wantChecks) {
+				t.Fatalf("checks = %+v, want %+v", got.Checks, tt.wantChecks)
+			}
+			for i, check := range got.Checks {
+				// The latency varies, only the rest of the result is compared.
+				check.LatencyMs = 0
+				if check != tt.wantChecks[i] {
+					t.Errorf("check %v = %+v, want %+v", i, check, tt.wantChecks[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/metrics"
+)
+
+// Methods and outcomes of auth_logins_total.
+const (
+	LOGIN_METHOD_PASSWORD = "password"
+	LOGIN_METHOD_OIDC     = "oidc"
+
+	LOGIN_OUTCOME_SUCCESS             = "success"
+	LOGIN_OUTCOME_INVALID_CREDENTIALS = "invalid_credentials"
+	LOGIN_OUTCOME_REJECTED            = "rejected"
+	LOGIN_OUTCOME_ERROR               = "error"
+)
+
+var logins = metrics.NewCounter("auth_logins_total",
+	"Interactive logins, by method and outcome.", "method", "outcome")
+
+// recordLogin counts a login by the error it ended with. Logins of disabled users
+// or users that have to reset their password are rejected, other failures are errors.
+func recordLogin(method string, err error) {
+	outcome := LOGIN_OUTCOME_ERROR
+	switch {
+	case err == nil:
+		outcome = LOGIN_OUTCOME_SUCCESS
+	case errors.Is(err, ErrInvalidCredentials):
+		outcome = LOGIN_OUTCOME_INVALID_CREDENTIALS
+	case apperror.KindOf(err) == apperror.UNAUTHORIZED, apperror.KindOf(err) == apperror.FORBIDDEN:
+		outcome = LOGIN_OUTCOME_REJECTED
+	}
+	logins.Inc(method, outcome)
+}
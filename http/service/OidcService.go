@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/oidc"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/security"
+)
+
+const (
+	// Time the user has to complete the sign in at the identity provider.
+	OIDC_FLOW_EXPIRATION_TIME   = 10 * time.Minute
+	DEFAULT_OIDC_USERNAME_CLAIM = "preferred_username"
+)
+
+var DEFAULT_OIDC_SCOPES = []string{"openid", "profile", "email"}
+
+type OidcService struct {
+	users     repository.UserRepository
+	tokens    *security.Tokens
+	passwords *security.Passwords
+	// Sign in with the provider is disabled without an issuer.
+	providerConfig oidc.Config
+	// Claim of the ID token that names new users.
+	usernameClaim string
+
+	provider   *oidc.Provider
+	providerMu sync.Mutex
+}
+
+func NewOidcService(users repository.UserRepository, tokens *security.Tokens, passwords *security.Passwords, oidcConfig config.OIDCConfig) *OidcService {
+	providerConfig := oidc.Config{
+		Issuer:       oidcConfig.Issuer,
+		ClientID:     oidcConfig.ClientID,
+		ClientSecret: oidcConfig.ClientSecret,
+		RedirectURL:  oidcConfig.RedirectURL,
+		Scopes:       oidcConfig.Scopes,
+	}
+	if len(providerConfig.Scopes) == 0 {
+		providerConfig.Scopes = DEFAULT_OIDC_SCOPES
+	}
+	usernameClaim := oidcConfig.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = DEFAULT_OIDC_USERNAME_CLAIM
+	}
+	return &OidcService{users: users, tokens: tokens, passwords: passwords, providerConfig: providerConfig, usernameClaim: usernameClaim}
+}
+
+// oidcFlowClaims carries the state of a sign in between the redirect to the provider and the callback.
+type oidcFlowClaims struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// The signed in user that links the identity, empty for a sign in.
+	LinkUsername string `json:"link_username,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (s *OidcService) OidcEnabled() bool {
+	return s.providerConfig.Issuer != ""
+}
+
+// StartOidcLogin returns the url of the provider to redirect to
+// and the signed flow state that has to be presented again in FinishOidcLogin.
+func (s *OidcService) StartOidcLogin(ctx context.Context) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "OidcService.StartOidcLogin")
+	defer span.End()
+	return s.startOidcFlow(ctx, "")
+}
+
+// StartOidcLink is StartOidcLogin for a signed in user, whose account is linked
+// to the identity the provider asserts in FinishOidcLogin.
+func (s *OidcService) StartOidcLink(ctx context.Context, username string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "OidcService.StartOidcLink")
+	defer span.End()
+	return s.startOidcFlow(ctx, username)
+}
+
+func (s *OidcService) startOidcFlow(ctx context.Context, linkUsername string) (string, string, error) {
+	provider, err := s.getOidcProvider(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in StartOidcLogin", "error", err)
+		return "", "", fmt.Errorf("identity provider is not available")
+	}
+	flow := oidcFlowClaims{
+		LinkUsername: linkUsername,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(OIDC_FLOW_EXPIRATION_TIME)),
+		},
+	}
+	if flow.State, err = oidc.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	if flow.Nonce, err = oidc.RandomToken(32); err != nil {
+		return "", "", err
+	}
+	if flow.CodeVerifier, err = oidc.NewCodeVerifier(); err != nil {
+		return "", "", err
+	}
+	signedFlow, err := jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString(s.oidcFlowKey())
+	if err != nil {
+		return "", "", fmt.Errorf("could not sign oidc flow: %w", err)
+	}
+	return provider.AuthCodeURL(flow.State, flow.Nonce, flow.CodeVerifier), signedFlow, nil
+}
+
+// FinishOidcLogin redeems the authorization code, links or provisions the user
+// and returns a token for our own api.
+// A CONFLICT error is returned if the identity is unknown and its username is taken.
+func (s *OidcService) FinishOidcLogin(ctx context.Context, code string, state string, signedFlow string) (_ *string, err error) {
+	ctx, span := tracer.Start(ctx, "OidcService.FinishOidcLogin")
+	defer span.End()
+	defer func() { recordLogin(LOGIN_METHOD_OIDC, err) }()
+	flow := &oidcFlowClaims{}
+	_, err = jwt.ParseWithClaims(signedFlow, flow, func(token *jwt.Token) (interface{}, error) {
+		return s.oidcFlowKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("sign in expired or was not started")
+	}
+	if state == "" || state != flow.State {
+		return nil, fmt.Errorf("state mismatch")
+	}
+
+	provider, err := s.getOidcProvider(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("identity provider is not available")
+	}
+	tokenResponse, err := provider.Exchange(ctx, code, flow.CodeVerifier)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("could not redeem authorization code")
+	}
+	idToken, err := provider.VerifyIDToken(ctx, tokenResponse.IDToken, flow.Nonce)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("could not verify id token")
+	}
+
+	username, err := s.linkOidcIdentity(ctx, idToken, flow.LinkUsername)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("could not sign in user: %v: %w", idToken.Subject, err)
+	}
+	account, err := s.users.GetUserAccount(ctx, username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("could not get the account of the user: %v", username)
+	}
+	if err := checkAccountActive(account, username); err != nil {
+		return nil, err
+	}
+	token, err := issueToken(ctx, s.users, s.tokens, username, LOGIN_METHOD_OIDC)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in FinishOidcLogin", "error", err)
+		return nil, fmt.Errorf("could not login the user: %v", username)
+	}
+	return &token, nil
+}
+
+// linkOidcIdentity returns the user linked to the identity.
+// An unknown identity is linked to linkUsername, the user that started the flow, if set.
+// Otherwise a new user named by the username claim is created for it. The claim never selects
+// an existing user, as anyone able to set it at the provider could take over that account.
+func (s *OidcService) linkOidcIdentity(ctx context.Context, idToken *oidc.IDTokenClaims, linkUsername string) (string, error) {
+	identity, err := s.users.GetUserIdentity(ctx, idToken.Issuer, idToken.Subject)
+	if err != nil {
+		return "", err
+	}
+	if identity != nil {
+		if linkUsername != "" && identity.Username != linkUsername {
+			return "", apperror.Conflict("identity is already linked to another user")
+		}
+		return identity.Username, nil
+	}
+	if linkUsername != "" {
+		err = s.users.LinkUserIdentity(ctx, entities.UserIdentity{
+			Issuer:    idToken.Issuer,
+			Subject:   idToken.Subject,
+			Username:  linkUsername,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return "", err
+		}
+		return linkUsername, nil
+	}
+
+	claim := s.usernameClaim
+	if claim == "email" && !idToken.EmailVerified {
+		return "", fmt.Errorf("email of %v is not verified", idToken.Subject)
+	}
+	username := idToken.Claim(claim)
+	if !entities.ValidUsername(username) {
+		return "", apperror.Unauthorized("claim %v of the identity is not a valid username: %q", claim, username)
+	}
+	// Provisioned users get a random password, so they can only sign in through the provider.
+	randomPassword, err := randomString(32, hex.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	hashedPassword, err := s.passwords.HashPassword(randomPassword)
+	if err != nil {
+		return "", err
+	}
+	err = s.users.CreateUserWithIdentity(ctx, entities.UserIdentity{
+		Issuer:    idToken.Issuer,
+		Subject:   idToken.Subject,
+		Username:  username,
+		CreatedAt: time.Now(),
+	}, hashedPassword)
+	if apperror.KindOf(err) == apperror.CONFLICT {
+		return "", apperror.Conflict("username is already taken: %v, sign in and link the identity to that account instead", username).Wrap(err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return username, nil
+}
+
+// The discovery document is fetched once and reused by warm invocations.
+func (s *OidcService) getOidcProvider(ctx context.Context) (*oidc.Provider, error) {
+	s.providerMu.Lock()
+	defer s.providerMu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	if !s.OidcEnabled() {
+		return nil, fmt.Errorf("OIDC_ISSUER is not set")
+	}
+	provider, err := oidc.NewProvider(ctx, s.providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	s.provider = provider
+	return s.provider, nil
+}
+
+// The flow state is signed with a key derived from JWT_SECRET,
+// so it can never be accepted as an api token.
+func (s *OidcService) oidcFlowKey() []byte {
+	return s.tokens.DeriveKey("oidc-flow")
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/oidc/oidctest"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+	"github.com/skyrenx/blog-api-go/http/security"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	TEST_CLIENT_ID    = "blog-api"
+	TEST_SUBJECT      = "idp-user-1"
+	TEST_REDIRECT_URL = "http://localhost:3000/User/oidc/callback"
+)
+
+type oidcTestEnv struct {
+	server  *oidctest.Server
+	users   *repository.Memory
+	tokens  *security.Tokens
+	service *OidcService
+}
+
+func newOidcTestEnv(t *testing.T, username string) *oidcTestEnv {
+	t.Helper()
+	server, err := oidctest.NewServer(TEST_CLIENT_ID, TEST_SUBJECT, jwt.MapClaims{"preferred_username": username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	users := repository.NewMemory()
+	tokens := security.NewTokens(config.TokenConfig{}, secrets.Static("test-secret"))
+	passwords, err := security.NewPasswords(config.PasswordConfig{BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewOidcService(users, tokens, passwords, config.OIDCConfig{
+		Issuer:      server.URL,
+		ClientID:    TEST_CLIENT_ID,
+		RedirectURL: TEST_REDIRECT_URL,
+	})
+	return &oidcTestEnv{server: server, users: users, tokens: tokens, service: service}
+}
+
+// signIn runs the flow from the redirect to the provider to the callback, as a browser would,
+// and returns the username of the issued token.
+func (e *oidcTestEnv) signIn(t *testing.T, linkUsername string) (string, error) {
+	t.Helper()
+	ctx := context.Background()
+	var redirectURL, signedFlow string
+	var err error
+	if linkUsername == "" {
+		redirectURL, signedFlow, err = e.service.StartOidcLogin(ctx)
+	} else {
+		redirectURL, signedFlow, err = e.service.StartOidcLink(ctx, linkUsername)
+	}
+	if err != nil {
+		t.Fatalf("starting the flow failed: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(redirectURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid callback: %v", err)
+	}
+
+	token, err := e.service.FinishOidcLogin(ctx, callback.Query().Get("code"), callback.Query().Get("state"), signedFlow)
+	if err != nil {
+		return "", err
+	}
+	claims, err := e.tokens.ParseJWT(*token)
+	if err != nil {
+		t.Fatalf("issued token is invalid: %v", err)
+	}
+	return claims.Username, nil
+}
+
+func (e *oidcTestEnv) register(t *testing.T, username string) {
+	t.Helper()
+	err := e.users.RegisterUser(context.Background(), entities.User{Username: username, Password: "$2a$04$local", Enabled: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *oidcTestEnv) linkedUsername(t *testing.T) string {
+	t.Helper()
+	identity, err := e.users.GetUserIdentity(context.Background(), e.server.URL, TEST_SUBJECT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity == nil {
+		return ""
+	}
+	return identity.Username
+}
+
+func TestOidcSignIn(t *testing.T) {
+	tests := []struct {
+		name         string
+		claim        string
+		overrides    jwt.MapClaims
+		setup        func(t *testing.T, e *oidcTestEnv)
+		linkUsername string
+		wantUsername string
+		wantKind     apperror.Kind
+		wantError    bool
+		wantLinked   string
+	}{
+		{
+			name:         "new identity creates a user",
+			claim:        "alice",
+			wantUsername: "alice",
+			wantLinked:   "alice",
+		},
+		{
+			name:  "known identity signs in its user",
+			claim: "renamed-at-idp",
+			setup: func(t *testing.T, e *oidcTestEnv) {
+				e.register(t, "alice")
+				e.users.LinkUserIdentity(context.Background(), entities.UserIdentity{
+					Issuer: e.server.URL, Subject: TEST_SUBJECT, Username: "alice", CreatedAt: time.Now()})
+			},
+			wantUsername: "alice",
+			wantLinked:   "alice",
+		},
+		{
+			name:     "taken username is not taken over",
+			claim:    "admin",
+			setup:    func(t *testing.T, e *oidcTestEnv) { e.register(t, "admin") },
+			wantKind: apperror.CONFLICT,
+		},
+		{
+			name:     "username claim breaking the registration rules",
+			claim:    "al ice",
+			wantKind: apperror.UNAUTHORIZED,
+		},
+		{
+			name:     "too short username claim",
+			claim:    "al",
+			wantKind: apperror.UNAUTHORIZED,
+		},
+		{
+			name:         "signed in user links the identity",
+			claim:        "someone-else",
+			setup:        func(t *testing.T, e *oidcTestEnv) { e.register(t, "bob") },
+			linkUsername: "bob",
+			wantUsername: "bob",
+			wantLinked:   "bob",
+		},
+		{
+			name:  "identity linked to another user cannot be linked again",
+			claim: "alice",
+			setup: func(t *testing.T, e *oidcTestEnv) {
+				e.register(t, "alice")
+				e.register(t, "bob")
+				e.users.LinkUserIdentity(context.Background(), entities.UserIdentity{
+					Issuer: e.server.URL, Subject: TEST_SUBJECT, Username: "alice", CreatedAt: time.Now()})
+			},
+			linkUsername: "bob",
+			wantKind:     apperror.CONFLICT,
+			wantLinked:   "alice",
+		},
+		{
+			name:      "nonce mismatch",
+			claim:     "alice",
+			overrides: jwt.MapClaims{"nonce": "replayed-nonce"},
+			wantError: true,
+		},
+		{
+			name:      "wrong audience",
+			claim:     "alice",
+			overrides: jwt.MapClaims{"aud": "another-client"},
+			wantError: true,
+		},
+		{
+			name:      "expired id token",
+			claim:     "alice",
+			overrides: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newOidcTestEnv(t, tt.claim)
+			e.server.Overrides = tt.overrides
+			if tt.setup != nil {
+				tt.setup(t, e)
+			}
+			username, err := e.signIn(t, tt.linkUsername)
+			switch {
+			case tt.wantKind != 0:
+				if apperror.KindOf(err) != tt.wantKind {
+					t.Fatalf("error = %v, want kind %v", err, tt.wantKind)
+				}
+			case tt.wantError:
+				if err == nil {
+					t.Fatal("sign in succeeded")
+				}
+			case err != nil:
+				t.Fatalf("sign in failed: %v", err)
+			case username != tt.wantUsername:
+				t.Fatalf("token is for %q, want %q", username, tt.wantUsername)
+			}
+			if linked := e.linkedUsername(t); linked != tt.wantLinked {
+				t.Errorf("identity is linked to %q, want %q", linked, tt.wantLinked)
+			}
+		})
+	}
+}
+
+func TestOidcStateMismatch(t *testing.T) {
+	e := newOidcTestEnv(t, "alice")
+	_, signedFlow, err := e.service.StartOidcLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.service.FinishOidcLogin(context.Background(), "code", "forged-state", signedFlow); err == nil {
+		t.Fatal("sign in with a forged state succeeded")
+	}
+}
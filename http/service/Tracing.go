@@ -0,0 +1,8 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+const TRACER_NAME = "github.com/skyrenx/blog-api-go/http/service"
+
+// Every service call that takes a context is traced as a span named like "UserService.Login".
+var tracer = otel.Tracer(TRACER_NAME)
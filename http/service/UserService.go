@@ -1,36 +1,160 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/skyrenx/blog-api-go/http/apperror"
 	"github.com/skyrenx/blog-api-go/http/entities"
 	"github.com/skyrenx/blog-api-go/http/entities/dto"
 	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/security"
 )
 
-func GetUserByUsername(username string) (*dto.UserWithoutPassword, error) {
-	r, err := repository.GetUserByUsername(username)
+// The same error for unknown users and wrong passwords, so usernames cannot be probed.
+var ErrInvalidCredentials = apperror.Unauthorized("invalid username or password")
+
+type UserService struct {
+	users     repository.UserRepository
+	tokens    *security.Tokens
+	passwords *security.Passwords
+}
+
+func NewUserService(users repository.UserRepository, tokens *security.Tokens, passwords *security.Passwords) *UserService {
+	return &UserService{users: users, tokens: tokens, passwords: passwords}
+}
+
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*dto.UserWithoutPassword, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByUsername")
+	defer span.End()
+	r, err := s.users.GetUserByUsername(ctx, username)
 	if err != nil {
-		fmt.Printf("Error in GetUserByUsername: %v\n", err.Error())
-		return nil, fmt.Errorf("could not get the username of the user: %v", username)
+		slog.ErrorContext(ctx, "Error in GetUserByUsername", "error", err)
+		return nil, fmt.Errorf("could not get the username of the user: %v: %w", username, err)
 	}
 	return r, nil
 }
 
-func Register(user entities.User) error {
-	err := repository.RegisterUser(user)
+// ValidatePassword checks a new password of the user against the password policy.
+func (s *UserService) ValidatePassword(username string, password string) error {
+	if err := s.passwords.CheckPasswordPolicy(username, password); err != nil {
+		return apperror.Validation("password rejected: %v", err)
+	}
+	return nil
+}
+
+func (s *UserService) Register(ctx context.Context, request dto.RegisterRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.Register")
+	defer span.End()
+	hashedPassword, err := s.passwords.HashPassword(request.Password)
 	if err != nil {
-		fmt.Printf("Error in Register: %v\n", err.Error())
-		return fmt.Errorf("could not register the user: %v", user.Username)
+		return err
+	}
+	user := entities.User{Username: request.Username, Password: hashedPassword, Enabled: true}
+	if err := s.users.RegisterUser(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "Error in Register", "error", err)
+		return fmt.Errorf("could not register the user: %v: %w", user.Username, err)
 	}
 	return nil
 }
 
-func Login(user entities.User) (*string, error) {
-	token, err := repository.Login(user)
+func (s *UserService) Login(ctx context.Context, userCredentials dto.LoginRequest) (*string, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login")
+	defer span.End()
+	token, err := s.login(ctx, userCredentials)
+	recordLogin(LOGIN_METHOD_PASSWORD, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in Login", "error", err)
+		return nil, fmt.Errorf("could not login the user: %v: %w", userCredentials.Username, err)
+	}
+	return token, nil
+}
+
+func (s *UserService) login(ctx context.Context, userCredentials dto.LoginRequest) (*string, error) {
+	foundUser, err := s.users.GetUserWithPassword(ctx, userCredentials.Username)
+	if err != nil {
+		return nil, err
+	}
+	if foundUser == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Compare the provided password with the stored hashed password
+	if !security.VerifyPassword(foundUser.Password, userCredentials.Password) {
+		return nil, ErrInvalidCredentials
+	}
+	if !foundUser.Enabled {
+		return nil, apperror.Forbidden("user is disabled: %v", userCredentials.Username)
+	}
+	if foundUser.PasswordResetRequired {
+		return nil, apperror.Forbidden("user has to reset the password: %v", userCredentials.Username)
+	}
+	// The plain password is only known here, so this is the only chance to upgrade old hashes.
+	if s.passwords.NeedsRehash(foundUser.Password) {
+		if err := s.rehashPassword(ctx, userCredentials); err != nil {
+			slog.WarnContext(ctx, "Unable to rehash password", "error", err)
+		}
+	}
+	token, err := issueToken(ctx, s.users, s.tokens, userCredentials.Username, LOGIN_METHOD_PASSWORD)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// issueToken returns a new token of the user and records its session.
+func issueToken(ctx context.Context, users repository.UserRepository, tokens *security.Tokens, username string, loginMethod string) (string, error) {
+	token, claims, err := tokens.GenerateJWT(username)
 	if err != nil {
-		fmt.Printf("Error in Login: %v\n", err.Error())
-		return nil, fmt.Errorf("could not login the user: %v", user.Username)
+		return "", fmt.Errorf("could not generate token: %v: %w", username, err)
+	}
+	session := entities.Session{ID: claims.ID, Username: username, LoginMethod: loginMethod,
+		CreatedAt: claims.IssuedAt.Time.UTC(), ExpiresAt: claims.ExpiresAt.Time.UTC()}
+	if err := users.CreateSession(ctx, session); err != nil {
+		return "", fmt.Errorf("could not record the session: %v: %w", username, err)
 	}
 	return token, nil
-}
\ No newline at end of file
+}
+
+func (s *UserService) rehashPassword(ctx context.Context, userCredentials dto.LoginRequest) error {
+	hashedPassword, err := s.passwords.HashPassword(userCredentials.Password)
+	if err != nil {
+		return err
+	}
+	// Only the hash changes, so the tokens of the user stay valid.
+	_, err = s.users.RehashPassword(ctx, userCredentials.Username, hashedPassword)
+	return err
+}
+
+// ChangePassword replaces the password of the user after checking the current one.
+// This also completes a password reset forced by an administrator.
+func (s *UserService) ChangePassword(ctx context.Context, request dto.ChangePasswordRequest) error {
+	ctx, span := tracer.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+	user, err := s.users.GetUserWithPassword(ctx, request.Username)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in ChangePassword", "error", err)
+		return fmt.Errorf("could not change the password of the user: %v", request.Username)
+	}
+	if user == nil || !user.Enabled || !security.VerifyPassword(user.Password, request.CurrentPassword) {
+		return ErrInvalidCredentials
+	}
+	if err := s.ValidatePassword(request.Username, request.NewPassword); err != nil {
+		return err
+	}
+	if request.NewPassword == request.CurrentPassword {
+		return apperror.Validation("password rejected: new password must be different from the current password")
+	}
+	hashedPassword, err := s.passwords.HashPassword(request.NewPassword)
+	if err != nil {
+		return err
+	}
+	// Revokes the tokens issued so far, including any that were stolen.
+	if _, err := s.users.UpdatePassword(ctx, request.Username, hashedPassword, time.Now().UTC()); err != nil {
+		slog.ErrorContext(ctx, "Error in ChangePassword", "error", err)
+		return fmt.Errorf("could not change the password of the user: %v", request.Username)
+	}
+	return nil
+}
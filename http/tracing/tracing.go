@@ -0,0 +1,97 @@
+// Package tracing sets up OpenTelemetry tracing of the api.
+//
+// Packages create their spans with the global tracer provider of otel, which does nothing
+// until Setup installed an exporter. Trace context is propagated with the W3C traceparent
+// and baggage headers, so the spans of the api join the trace of the caller.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	DEFAULT_OTLP_ENDPOINT = "http://localhost:4318"
+	DEFAULT_SERVICE_NAME  = "blog-api-go"
+	// Limit of exporting the spans of a Lambda invocation.
+	FLUSH_TIMEOUT = 2 * time.Second
+)
+
+var provider *sdktrace.TracerProvider
+
+// Setup installs the tracer provider and propagator of tracingConfig as the globals of otel,
+// nothing is installed with config.EXPORTER_NONE.
+// version is reported as the service.version of the spans.
+func Setup(ctx context.Context, tracingConfig config.TracingConfig, version string) error {
+	if tracingConfig.Exporter == config.EXPORTER_NONE {
+		return nil
+	}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch tracingConfig.Exporter {
+	case config.EXPORTER_OTLP:
+		endpoint := tracingConfig.OTLPEndpoint
+		if endpoint == "" {
+			endpoint = DEFAULT_OTLP_ENDPOINT
+		}
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	case config.EXPORTER_STDOUT:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		err = fmt.Errorf("unknown tracing exporter: %v", tracingConfig.Exporter)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create span exporter: %w", err)
+	}
+
+	serviceName := tracingConfig.ServiceName
+	if serviceName == "" {
+		serviceName = DEFAULT_SERVICE_NAME
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName), semconv.ServiceVersion(version)))
+	if err != nil {
+		return fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Callers that sampled a trace get the spans of the api, whatever the ratio.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return nil
+}
+
+// Flush exports the spans ended so far, e.g. before a Lambda execution environment is frozen.
+func Flush(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), FLUSH_TIMEOUT)
+	defer cancel()
+	return provider.ForceFlush(ctx)
+}
+
+// Shutdown exports the remaining spans and stops the exporter.
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, FLUSH_TIMEOUT)
+	defer cancel()
+	return provider.Shutdown(ctx)
+}
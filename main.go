@@ -2,42 +2,111 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
 
-	"github.com/skyrenx/blog-api-go/http/controller"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/logging"
+	"github.com/skyrenx/blog-api-go/http/metrics"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+	"github.com/skyrenx/blog-api-go/http/tracing"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	_ "github.com/aws/aws-sdk-go-v2/aws"
-	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
 	"github.com/gin-gonic/gin"
 )
 
-var ginLambda *ginadapter.GinLambda
-
-func init() {
-
-	// Create your Gin router and define routes.
-	router := gin.Default()
-	router.SetTrustedProxies(nil)
-	//http://localhost:3000/BlogEntry?pageSize=1&pageNumber=1
-	router.GET("/BlogEntry", controller.GetBlogEntries)
-	//http://localhost:3000/BlogEntrySummary?pageSize=1&pageNumber=1
-	router.GET("/BlogEntrySummary", controller.GetBlogEntrySummaries)
-	router.GET("/BlogEntry/:id", controller.GetBlogEntryById)
-	router.POST("/BlogEntry", controller.CreateBlogEntry)
-	router.GET("/User/:username", controller.GetUserByUsername)
-	router.POST("/User/register", controller.Register)
-	router.GET("/User/login", controller.Login)
-
-	// Wrap the router with the Lambda adapter.
-	ginLambda = ginadapter.New(router)
-}
+func main() {
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	logging.Setup(cfg.LogLevel)
+
+	// The migrate subcommand needs only the database.
+	migrate := len(args) > 0 && args[0] == "migrate"
+	if len(args) > 0 && !migrate {
+		fmt.Fprintf(os.Stderr, "Unknown command: %v\n", args[0])
+		os.Exit(2)
+	}
+	validate := cfg.Validate
+	if migrate {
+		validate = cfg.Database.Validate
+	}
+	if err := validate(); err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
 
-// Assume ginLambda is declared and initialized in init()
-func handler(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return ginLambda.ProxyWithContext(ctx, req)
+	ctx := context.Background()
+	resolver := secrets.NewResolver(cfg.Region, cfg.SecretsRefreshInterval)
+	if err := resolveSecrets(ctx, cfg, resolver); err != nil {
+		slog.Error("Unable to resolve secrets", "error", err)
+		os.Exit(1)
+	}
+
+	// DB_BACKEND=memory runs the api without a database, e.g. with sam local.
+	repositories, err := repository.New(cfg.Database)
+	if err != nil {
+		slog.Error("Unable to create repositories", "error", err)
+		os.Exit(1)
+	}
+	if migrate {
+		os.Exit(runMigrate(repositories.Migrator, args[1:]))
+	}
+	// DB_MIGRATE_ON_STARTUP=true applies pending migrations before serving requests.
+	if cfg.Features.MigrateOnStartup && repositories.Migrator != nil {
+		if _, err := repositories.Migrator.Up(ctx, false); err != nil {
+			slog.Error("Unable to migrate the database", "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := tracing.Setup(ctx, cfg.Tracing, buildInfo().Commit); err != nil {
+		slog.Error("Unable to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	jwtSecret, err := resolver.Resolve(ctx, cfg.Tokens.Secret)
+	if err != nil {
+		slog.Error("Unable to resolve secrets", "error", err)
+		os.Exit(1)
+	}
+	router, err := newRouter(cfg, repositories, jwtSecret)
+	if err != nil {
+		slog.Error("Unable to create the router", "error", err)
+		os.Exit(1)
+	}
+
+	// Outside of Lambda the same router is served over plain HTTP, e.g. for local development,
+	// and Prometheus scrapes the metrics. In Lambda they are logged in Embedded Metric Format instead.
+	if !isLambda() {
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+		code := runServer(router, cfg.HTTP)
+		if err := tracing.Shutdown(ctx); err != nil {
+			slog.Error("Unable to export the remaining spans", "error", err)
+		}
+		os.Exit(code)
+	}
+	metrics.EnableEMF(cfg.MetricsNamespace)
+	lambda.Start(newLambdaHandler(router).handle)
 }
 
-func main() {
-	lambda.Start(handler)
+// resolveSecrets replaces the references in DATABASE_URL and OIDC_CLIENT_SECRET with their values,
+// which are only read at startup. JWT_SECRET is resolved by its user, so it can be rotated.
+func resolveSecrets(ctx context.Context, cfg *config.Config, resolver *secrets.Resolver) error {
+	for _, value := range []*string{&cfg.Database.DatabaseURL, &cfg.OIDC.ClientSecret} {
+		secret, err := resolver.Resolve(ctx, *value)
+		if err != nil {
+			return err
+		}
+		*value = secret.Value()
+	}
+	return nil
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/skyrenx/blog-api-go/http/repository"
+)
+
+const MIGRATE_USAGE = `Usage: blog-api-go [flags] migrate [-dry-run] <command>
+
+Commands:
+  up        apply all pending migrations
+  down [n]  revert the last n applied migrations, 1 by default
+  status    list the migrations and whether they are applied
+
+The database is selected with the same settings as the api, e.g. DB_BACKEND or -db-backend.
+`
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(migrator *repository.Migrator, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, MIGRATE_USAGE) }
+	dryRun := flags.Bool("dry-run", false, "print the statements without running them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if migrator == nil {
+		fmt.Fprintln(os.Stderr, "The database backend has no schema to migrate")
+		return 1
+	}
+
+	ctx := context.Background()
+	var err error
+	switch flags.Arg(0) {
+	case "up":
+		_, err = migrator.Up(ctx, *dryRun)
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			if steps, err = strconv.Atoi(flags.Arg(1)); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid number of migrations: %v\n", flags.Arg(1))
+				return 2
+			}
+		}
+		_, err = migrator.Down(ctx, steps, *dryRun)
+	case "status":
+		var statuses []repository.MigrationStatus
+		statuses, err = migrator.Status(ctx)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Dirty {
+				state = "dirty"
+			} else if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-40s %v\n", status.Version, status.Name, state)
+		}
+	default:
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/skyrenx/blog-api-go/http/apperror"
+	"github.com/skyrenx/blog-api-go/http/config"
+	"github.com/skyrenx/blog-api-go/http/controller"
+	"github.com/skyrenx/blog-api-go/http/entities"
+	"github.com/skyrenx/blog-api-go/http/middleware"
+	"github.com/skyrenx/blog-api-go/http/ratelimit"
+	"github.com/skyrenx/blog-api-go/http/repository"
+	"github.com/skyrenx/blog-api-go/http/secrets"
+	"github.com/skyrenx/blog-api-go/http/security"
+	"github.com/skyrenx/blog-api-go/http/service"
+)
+
+// newRouter wires the services and controllers on top of the given repositories.
+// Tokens are signed with jwtSecret, the resolved JWT_SECRET.
+func newRouter(cfg *config.Config, repositories *repository.Repositories, jwtSecret *secrets.Secret) (*gin.Engine, error) {
+	blogEntries := repositories.BlogEntries
+	users := repositories.Users
+	apiKeys := repositories.ApiKeys
+	auditLog := repositories.AuditLog
+
+	tokens := security.NewTokens(cfg.Tokens, jwtSecret)
+	passwords, err := security.NewPasswords(cfg.Passwords)
+	if err != nil {
+		return nil, err
+	}
+	apiKeyService := service.NewApiKeyService(apiKeys)
+	authService := service.NewAuthService(users, apiKeyService, tokens)
+
+	blogEntryController := controller.NewBlogEntryController(service.NewBlogEntryService(blogEntries, cfg.BlogEntryIDStrategy))
+	userController := controller.NewUserController(service.NewUserService(users, tokens, passwords))
+	apiKeyController := controller.NewApiKeyController(apiKeyService)
+	oidcController := controller.NewOidcController(service.NewOidcService(users, tokens, passwords, cfg.OIDC))
+	accountController := controller.NewAccountController(service.NewAccountService(users, blogEntries, apiKeys, auditLog))
+	adminController := controller.NewAdminController(service.NewAdminService(users))
+
+	checks := []service.HealthCheck{service.DatabaseCheck(repositories.Database), service.SigningKeyCheck(jwtSecret)}
+	if repositories.Migrator != nil {
+		checks = append(checks, service.MigrationsCheck(repositories.Migrator))
+	}
+	healthController := controller.NewHealthController(service.NewHealthService(checks...), buildInfo())
+
+	// The buckets of the rate limits live in this instance, a shared ratelimit.Store would limit all of them together.
+	rateLimits := ratelimit.NewMemoryStore()
+	routeLimits := make(map[string]ratelimit.Limit, len(cfg.RateLimit.Routes))
+	for route, limit := range cfg.RateLimit.Routes {
+		routeLimits[route] = ratelimit.Limit(limit)
+	}
+	authenticate := []gin.HandlerFunc{middleware.Authenticate(authService), middleware.RateLimit(rateLimits,
+		middleware.RateLimitPolicy{Name: "user", Limit: ratelimit.Limit(cfg.RateLimit.User), Key: middleware.PrincipalKey})}
+
+	// Create your Gin router and define routes.
+	// Instead of the text logger of gin.Default, requests are logged as JSON by AccessLog.
+	router := gin.New()
+	router.SetTrustedProxies(nil)
+	router.Use(middleware.Tracing(), middleware.RequestID(), middleware.AccessLog(), middleware.Metrics())
+	router.Use(middleware.RequestTimeout(cfg.HTTP.RequestTimeout))
+	// Inside RequestTimeout, so it still sees whether the request context ended.
+	router.Use(middleware.ErrorResponse())
+	router.Use(middleware.Recovery())
+	// Before the rate limits, so preflight requests take no token and errors still carry the CORS headers.
+	router.Use(middleware.SecurityHeaders(), middleware.CORS(cfg.CORS), middleware.BodyLimit(int64(cfg.HTTP.MaxBodySize)))
+	// Every request counts against the limit of its client, including those that fail to authenticate.
+	router.Use(middleware.RateLimit(rateLimits, middleware.RateLimitPolicy{Name: "client", Limit: ratelimit.Limit(cfg.RateLimit.Client), Key: middleware.ClientIPKey}))
+	router.Use(middleware.RouteRateLimit(rateLimits, routeLimits, middleware.ClientIPKey))
+	router.NoRoute(func(c *gin.Context) {
+		c.Error(apperror.NotFound("no route for %v %v", c.Request.Method, c.Request.URL.Path))
+	})
+	router.GET("/healthz", healthController.Healthz)
+	router.GET("/readyz", healthController.Readyz)
+	router.GET("/version", healthController.Version)
+	//http://localhost:3000/BlogEntry?pageSize=1&pageNumber=1
+	router.GET("/BlogEntry", blogEntryController.GetBlogEntries)
+	//http://localhost:3000/BlogEntrySummary?pageSize=1&pageNumber=1
+	router.GET("/BlogEntrySummary", blogEntryController.GetBlogEntrySummaries)
+	router.GET("/BlogEntry/:id", blogEntryController.GetBlogEntryById)
+	router.POST("/BlogEntry", append(authenticate,
+		middleware.RequireScope(entities.SCOPE_BLOG_ENTRIES_WRITE), blogEntryController.CreateBlogEntry)...)
+	router.GET("/User/:username", userController.GetUserByUsername)
+	if cfg.Features.Registration {
+		router.POST("/User/register", userController.Register)
+	}
+	router.GET("/User/login", userController.Login)
+	router.POST("/User/password", userController.ChangePassword)
+	router.GET("/User/oidc/login", oidcController.OidcLogin)
+	router.GET("/User/oidc/callback", oidcController.OidcCallback)
+
+	// Api keys and the account itself can only be managed with an interactive login.
+	apiKeyRoutes := router.Group("/User/apikeys", append(authenticate,
+		middleware.RequireAuthMethod(entities.AUTH_METHOD_JWT))...)
+	apiKeyRoutes.GET("", apiKeyController.GetApiKeys)
+	apiKeyRoutes.POST("", apiKeyController.CreateApiKey)
+	apiKeyRoutes.DELETE("/:id", apiKeyController.RevokeApiKey)
+	me := router.Group("/User/me", append(authenticate,
+		middleware.RequireAuthMethod(entities.AUTH_METHOD_JWT))...)
+	me.GET("/export", accountController.ExportAccount)
+	me.DELETE("", accountController.DeleteAccount)
+	me.GET("/oidc/link", oidcController.OidcLink)
+
+	admin := router.Group("/Admin/users", append(authenticate,
+		middleware.RequireAuthMethod(entities.AUTH_METHOD_JWT),
+		middleware.RequireAuthority(entities.AUTHORITY_ADMIN))...)
+	admin.GET("", adminController.SearchUsers)
+	admin.GET("/:username", adminController.GetUserAccount)
+	admin.DELETE("/:username", adminController.DeleteUser)
+	admin.PUT("/:username/enabled", adminController.SetUserEnabled)
+	admin.POST("/:username/password-reset", adminController.ForcePasswordReset)
+	admin.PUT("/:username/authorities/:authority", adminController.GrantAuthority)
+	admin.DELETE("/:username/authorities/:authority", adminController.RevokeAuthority)
+
+	return router, nil
+}
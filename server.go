@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/skyrenx/blog-api-go/http/config"
+)
+
+const (
+	// Protects against clients that open connections and send their headers slowly.
+	READ_HEADER_TIMEOUT = 10 * time.Second
+)
+
+// isLambda reports whether the process was started by the Lambda runtime, including sam local.
+func isLambda() bool {
+	return os.Getenv("AWS_LAMBDA_RUNTIME_API") != ""
+}
+
+// runServer serves handler over plain net/http until SIGINT or SIGTERM and returns the exit code.
+// TLS is enabled when a certificate and key are configured.
+// On a signal the server stops accepting connections and waits up to the shutdown timeout
+// for open requests to finish.
+func runServer(handler http.Handler, config config.HTTPConfig) int {
+	tls := config.TLSCertFile != ""
+	server := &http.Server{Addr: config.Addr, Handler: handler, ReadHeaderTimeout: READ_HEADER_TIMEOUT}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting HTTP server", "addr", config.Addr, "tls", tls)
+		if tls {
+			serveErr <- server.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		slog.Error("HTTP server failed", "error", err)
+		return 1
+	case <-ctx.Done():
+	}
+	// A second signal kills the process right away.
+	stop()
+
+	slog.Info("Shutting down HTTP server", "timeout", config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server did not shut down cleanly", "error", err)
+		return 1
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("HTTP server failed", "error", err)
+		return 1
+	}
+	slog.Info("HTTP server stopped")
+	return 0
+}
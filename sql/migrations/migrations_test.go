@@ -0,0 +1,99 @@
+package migrations
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "single statement",
+			script: "DROP TABLE users;\n",
+			want:   []string{"DROP TABLE users;"},
+		},
+		{
+			name:   "statement over several lines",
+			script: "CREATE TABLE t (\n    id INT,\n    name TEXT\n);\n",
+			want:   []string{"CREATE TABLE t (\n    id INT,\n    name TEXT\n);"},
+		},
+		{
+			name:   "several statements",
+			script: "DROP TABLE a;\nDROP TABLE b;\n",
+			want:   []string{"DROP TABLE a;", "DROP TABLE b;"},
+		},
+		{
+			name:   "comments and blank lines are dropped",
+			script: "-- Drop the tables.\n\nDROP TABLE a;\n  -- indented comment\n\nDROP TABLE b;\n",
+			want:   []string{"DROP TABLE a;", "DROP TABLE b;"},
+		},
+		{
+			name:   "semicolon within a line does not split",
+			script: "INSERT INTO t (name) VALUES ('a;b');\n",
+			want:   []string{"INSERT INTO t (name) VALUES ('a;b');"},
+		},
+		{
+			name:   "two statements on one line stay together",
+			script: "DROP TABLE a; DROP TABLE b;\n",
+			want:   []string{"DROP TABLE a; DROP TABLE b;"},
+		},
+		{
+			name:   "last statement without semicolon",
+			script: "DROP TABLE a;\nDROP TABLE b\n",
+			want:   []string{"DROP TABLE a;", "DROP TABLE b"},
+		},
+		{
+			name:   "windows line endings",
+			script: "DROP TABLE a;\r\nDROP TABLE b;\r\n",
+			want:   []string{"DROP TABLE a;", "DROP TABLE b;"},
+		},
+		{
+			name:   "only comments",
+			script: "-- nothing to do\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Statements(tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("Statements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range []string{DIALECT_POSTGRES, DIALECT_SQLITE} {
+		t.Run(dialect, func(t *testing.T) {
+			migrations, err := Load(dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) == 0 {
+				t.Fatal("no migrations")
+			}
+			for i, migration := range migrations {
+				if i > 0 && migration.Version <= migrations[i-1].Version {
+					t.Errorf("version %v follows %v, want ascending versions", migration.Version, migrations[i-1].Version)
+				}
+				if len(migration.Checksum) != 64 {
+					t.Errorf("checksum of %v = %q, want a SHA-256", migration.Version, migration.Checksum)
+				}
+				if len(Statements(migration.Up)) == 0 || len(Statements(migration.Down)) == 0 {
+					t.Errorf("migration %v_%v has an empty script", migration.Version, migration.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadUnknownDialect(t *testing.T) {
+	if _, err := Load("oracle"); err == nil || !strings.Contains(err.Error(), "unknown migration dialect") {
+		t.Fatalf("error = %v, want an unknown dialect", err)
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE blog_entries;
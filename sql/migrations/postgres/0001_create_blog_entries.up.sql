@@ -1,4 +1,5 @@
-CREATE TABLE blog_entries (
+-- IF NOT EXISTS lets databases created before the migrations adopt them.
+CREATE TABLE IF NOT EXISTS blog_entries (
     id INT PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
     content TEXT NOT NULL,
@@ -7,4 +8,3 @@ CREATE TABLE blog_entries (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     published BOOLEAN DEFAULT FALSE
 );
-
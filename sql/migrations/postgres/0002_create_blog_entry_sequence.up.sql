@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS blog_entry_sequence (
+    next_id INT NOT NULL
+);
+
+-- Initialize with starting value 1
+INSERT INTO blog_entry_sequence (next_id)
+SELECT 1 WHERE NOT EXISTS (SELECT 1 FROM blog_entry_sequence);
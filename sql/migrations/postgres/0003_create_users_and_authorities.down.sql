@@ -0,0 +1,2 @@
+DROP TABLE authorities;
+DROP TABLE users;
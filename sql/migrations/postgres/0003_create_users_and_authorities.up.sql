@@ -1,9 +1,5 @@
--- Drop tables if they exist
-DROP TABLE IF EXISTS authorities;
-DROP TABLE IF EXISTS users;
-
 -- Create the "users" table
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     username VARCHAR(50) NOT NULL,
     password CHAR(68) NOT NULL,
     enabled BOOLEAN NOT NULL,
@@ -11,8 +7,8 @@ CREATE TABLE users (
 );
 
 -- Create the "authorities" table without a foreign key constraint
-CREATE TABLE authorities (
+CREATE TABLE IF NOT EXISTS authorities (
     username VARCHAR(50) NOT NULL,
     authority VARCHAR(50) NOT NULL,
     UNIQUE (username, authority)
-);
\ No newline at end of file
+);
@@ -0,0 +1,15 @@
+-- Create the "api_keys" table.
+-- Only a SHA-256 hash of the key is stored, the plain key is shown to the user once.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(32) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE (key_hash)
+);
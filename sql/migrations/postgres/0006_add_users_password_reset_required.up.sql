@@ -0,0 +1,3 @@
+-- Add the forced password reset flag to the "users" table.
+-- NULL is treated as FALSE.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN;
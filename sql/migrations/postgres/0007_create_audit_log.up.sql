@@ -0,0 +1,11 @@
+-- Create the "audit_log" table.
+-- Rows are never updated or deleted by the api.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(32) NOT NULL,
+    actor VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target VARCHAR(50) NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,12 @@
+-- Fails if argon2id hashes are stored, reset those passwords first.
+CREATE TABLE IF NOT EXISTS users_old (
+    username VARCHAR(50) NOT NULL,
+    password CHAR(68) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    password_reset_required BOOLEAN,
+    PRIMARY KEY (username)
+);
+INSERT INTO users_old (username, password, enabled, password_reset_required)
+SELECT username, password, enabled, password_reset_required FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
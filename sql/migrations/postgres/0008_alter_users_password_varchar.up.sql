@@ -0,0 +1,16 @@
+-- argon2id hashes are longer than the 68 characters reserved for bcrypt.
+-- VARCHAR also stops padding bcrypt hashes with spaces, the cast drops the padding of stored hashes.
+-- Aurora DSQL can't change the type of a column, so the table is copied.
+-- Stop writes to users while this runs, users registered during the copy are lost.
+-- Aurora DSQL limits the rows one transaction may modify, a larger table has to be copied in batches by hand.
+CREATE TABLE IF NOT EXISTS users_new (
+    username VARCHAR(50) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    password_reset_required BOOLEAN,
+    PRIMARY KEY (username)
+);
+INSERT INTO users_new (username, password, enabled, password_reset_required)
+SELECT username, CAST(password AS VARCHAR(255)), enabled, password_reset_required FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
@@ -0,0 +1,14 @@
+-- Fails if UUID IDs are stored, delete those entries first.
+CREATE TABLE IF NOT EXISTS blog_entries_old (
+    id INT PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    author VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published BOOLEAN DEFAULT FALSE
+);
+INSERT INTO blog_entries_old (id, title, content, author, created_at, updated_at, published)
+SELECT CAST(id AS INT), title, content, author, created_at, updated_at, published FROM blog_entries;
+DROP TABLE blog_entries;
+ALTER TABLE blog_entries_old RENAME TO blog_entries;
@@ -0,0 +1,10 @@
+-- Create the "sessions" table.
+-- Every issued token is recorded with its ID, so the sessions of a user can be exported.
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(32) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    login_method VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,7 @@
+DROP TABLE audit_log;
+DROP TABLE user_identities;
+DROP TABLE api_keys;
+DROP TABLE authorities;
+DROP TABLE users;
+DROP TABLE blog_entry_sequence;
+DROP TABLE blog_entries;
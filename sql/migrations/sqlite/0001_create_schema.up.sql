@@ -0,0 +1,63 @@
+-- Schema of the SQLite backend, equivalent to the Postgres migrations up to 0008.
+CREATE TABLE IF NOT EXISTS blog_entries (
+    id INT PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    author VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published BOOLEAN DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS blog_entry_sequence (
+    next_id INT NOT NULL
+);
+
+INSERT INTO blog_entry_sequence (next_id)
+SELECT 1 WHERE NOT EXISTS (SELECT 1 FROM blog_entry_sequence);
+
+CREATE TABLE IF NOT EXISTS users (
+    username VARCHAR(50) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    password_reset_required BOOLEAN,
+    PRIMARY KEY (username)
+);
+
+CREATE TABLE IF NOT EXISTS authorities (
+    username VARCHAR(50) NOT NULL,
+    authority VARCHAR(50) NOT NULL,
+    UNIQUE (username, authority)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(32) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE (key_hash)
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(32) NOT NULL,
+    actor VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target VARCHAR(50) NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,15 @@
+-- Blog entry IDs become strings so that UUIDv7 IDs can be stored next to the integer IDs
+-- of the sequence strategy, which are kept as their decimal strings.
+-- SQLite can't change the type of a column, so the table is copied.
+CREATE TABLE blog_entries_new (
+    id VARCHAR(36) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    author VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published BOOLEAN DEFAULT FALSE
+);
+INSERT INTO blog_entries_new SELECT CAST(id AS TEXT), title, content, author, created_at, updated_at, published FROM blog_entries;
+DROP TABLE blog_entries;
+ALTER TABLE blog_entries_new RENAME TO blog_entries;
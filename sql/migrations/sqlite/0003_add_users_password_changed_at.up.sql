@@ -0,0 +1,3 @@
+-- Record when the password of a user was last changed.
+-- Tokens issued before are rejected, NULL means the password was never changed.
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP;
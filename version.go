@@ -0,0 +1,39 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/skyrenx/blog-api-go/http/entities/dto"
+)
+
+// Set at build time, see deploy.sh:
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	commit    string
+	buildTime string
+)
+
+// buildInfo describes the running build. Without -ldflags the commit and time
+// recorded by the go command are used, "unknown" if there are none, e.g. with go run.
+func buildInfo() dto.BuildInfo {
+	info := dto.BuildInfo{Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}